@@ -21,6 +21,8 @@
 package clock
 
 import (
+	"sort"
+	"sync"
 	"time"
 
 	// clockwork is not currently used but it is useful to have the option to use this in testing code
@@ -36,12 +38,34 @@ type (
 	TimeSource interface {
 		Now() time.Time
 	}
+	// TimerSource is a TimeSource which can also
+	// schedule functions, so that timers can be
+	// mocked out in unit test as well
+	TimerSource interface {
+		TimeSource
+		AfterFunc(d time.Duration, f func()) Timer
+	}
+	// Timer is a timer created by a TimerSource,
+	// *time.Timer satisfies it
+	Timer interface {
+		Reset(d time.Duration) bool
+		Stop() bool
+	}
+
 	// RealTimeSource serves real wall-clock time
 	RealTimeSource struct{}
 
 	// EventTimeSource serves fake controlled time
 	EventTimeSource struct {
-		now time.Time
+		sync.Mutex
+		now    time.Time
+		timers map[*eventTimer]struct{}
+	}
+
+	eventTimer struct {
+		timeSource *EventTimeSource
+		deadline   time.Time
+		f          func()
 	}
 )
 
@@ -56,6 +80,11 @@ func (ts *RealTimeSource) Now() time.Time {
 	return time.Now()
 }
 
+// AfterFunc calls f in its own goroutine after the duration elapsed
+func (ts *RealTimeSource) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
 // NewEventTimeSource returns a time source that servers
 // fake controlled time
 func NewEventTimeSource() *EventTimeSource {
@@ -64,11 +93,58 @@ func NewEventTimeSource() *EventTimeSource {
 
 // Now return the fake current time
 func (ts *EventTimeSource) Now() time.Time {
+	ts.Lock()
+	defer ts.Unlock()
 	return ts.now
 }
 
-// Update update the fake current time
+// Update update the fake current time, the functions
+// of the timers expired by then are called by Update
 func (ts *EventTimeSource) Update(now time.Time) *EventTimeSource {
+	ts.Lock()
 	ts.now = now
+	var expired []*eventTimer
+	for timer := range ts.timers {
+		if !timer.deadline.After(now) {
+			expired = append(expired, timer)
+			delete(ts.timers, timer)
+		}
+	}
+	ts.Unlock()
+
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expired[i].deadline.Before(expired[j].deadline)
+	})
+	for _, timer := range expired {
+		timer.f()
+	}
 	return ts
 }
+
+// AfterFunc calls f once the fake current time
+// is updated to after the duration elapsed
+func (ts *EventTimeSource) AfterFunc(d time.Duration, f func()) Timer {
+	timer := &eventTimer{timeSource: ts, f: f}
+	timer.Reset(d)
+	return timer
+}
+
+func (t *eventTimer) Reset(d time.Duration) bool {
+	t.timeSource.Lock()
+	defer t.timeSource.Unlock()
+	_, active := t.timeSource.timers[t]
+	if t.timeSource.timers == nil {
+		t.timeSource.timers = make(map[*eventTimer]struct{})
+	}
+	t.deadline = t.timeSource.now.Add(d)
+	t.timeSource.timers[t] = struct{}{}
+	return active
+}
+
+func (t *eventTimer) Stop() bool {
+	t.timeSource.Lock()
+	defer t.timeSource.Unlock()
+	_, active := t.timeSource.timers[t]
+	delete(t.timeSource.timers, t)
+	return active
+}
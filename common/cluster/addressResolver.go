@@ -0,0 +1,55 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+)
+
+type (
+	// AddressResolver resolves the RPC address of clusters using dynamic discovery, e.g. with DNS or Consul
+	AddressResolver interface {
+		Resolve(ctx context.Context, clusterName string) (string, error)
+	}
+)
+
+// GetClusterRPCAddress return the RPC address of the cluster. The address of clusters using dynamic discovery
+// is resolved by the address resolver, the static RPC address is returned for other clusters
+// or if there is no address resolver.
+func (m Metadata) GetClusterRPCAddress(ctx context.Context, clusterName string) (string, error) {
+	info, ok := m.GetAllClusterInfo()[clusterName]
+	if !ok {
+		return "", fmt.Errorf("unknown cluster name: %v", clusterName)
+	}
+	if !info.DynamicDiscovery || m.addressResolver == nil {
+		return info.RPCAddress, nil
+	}
+
+	address, err := m.addressResolver.Resolve(ctx, clusterName)
+	if err != nil {
+		return "", fmt.Errorf("cluster %v: failed to resolve rpc address: %w", clusterName, err)
+	}
+	if len(address) == 0 {
+		return "", fmt.Errorf("cluster %v: resolved rpc address is empty", clusterName)
+	}
+	return address, nil
+}
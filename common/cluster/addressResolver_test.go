@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+type fakeAddressResolver map[string]string
+
+func (r fakeAddressResolver) Resolve(_ context.Context, clusterName string) (string, error) {
+	address, ok := r[clusterName]
+	if !ok {
+		return "", errors.New("no service instance")
+	}
+	return address, nil
+}
+
+func TestMetadataGetClusterRPCAddress(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"static":     {Enabled: true, InitialFailoverVersion: 0, RPCAddress: "127.0.0.1:7933"},
+		"discovered": {Enabled: true, InitialFailoverVersion: 1, RPCAddress: "127.0.0.1:8933", DynamicDiscovery: true},
+		"missing":    {Enabled: true, InitialFailoverVersion: 2, RPCAddress: "127.0.0.1:9933", DynamicDiscovery: true},
+		"empty":      {Enabled: true, InitialFailoverVersion: 3, RPCAddress: "127.0.0.1:6933", DynamicDiscovery: true},
+	}
+	resolver := fakeAddressResolver{
+		"static":     "10.0.0.1:7933",
+		"discovered": "10.0.0.2:7933",
+		"empty":      "",
+	}
+	ctx := context.Background()
+
+	metadata := NewMetadata(TestFailoverVersionIncrement, "static", "static", clusterGroup, WithAddressResolver(resolver))
+	address, err := metadata.GetClusterRPCAddress(ctx, "static")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:7933", address)
+	address, err = metadata.GetClusterRPCAddress(ctx, "discovered")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.2:7933", address)
+	_, err = metadata.GetClusterRPCAddress(ctx, "missing")
+	assert.EqualError(t, err, "cluster missing: failed to resolve rpc address: no service instance")
+	_, err = metadata.GetClusterRPCAddress(ctx, "empty")
+	assert.EqualError(t, err, "cluster empty: resolved rpc address is empty")
+	_, err = metadata.GetClusterRPCAddress(ctx, "unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+
+	// the static address is the fallback without address resolver
+	metadata = NewMetadata(TestFailoverVersionIncrement, "static", "static", clusterGroup)
+	address, err = metadata.GetClusterRPCAddress(ctx, "discovered")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8933", address)
+}
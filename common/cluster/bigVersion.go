@@ -0,0 +1,62 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// FailoverVersionIncrementBig return the failover version increment as big.Int,
+// e.g. for tools scanning failover versions near the int64 limits
+func (m Metadata) FailoverVersionIncrementBig() *big.Int {
+	return big.NewInt(m.failoverVersionIncrement)
+}
+
+// FailoverGenerationBig return the generation of the failover version, the same as version / failoverVersionIncrement
+func (m Metadata) FailoverGenerationBig(failoverVersion *big.Int) *big.Int {
+	return new(big.Int).Quo(failoverVersion, m.FailoverVersionIncrementBig())
+}
+
+// ClusterNameForFailoverVersionBig is the same as ClusterNameForFailoverVersion for failover versions as big.Int,
+// but returns an error for failover versions not belonging to any cluster regardless of the unknown version policy.
+// Failover versions beyond the int64 range resolve by residue only.
+func (m Metadata) ClusterNameForFailoverVersionBig(failoverVersion *big.Int) (string, error) {
+	if failoverVersion == nil {
+		return "", errors.New("failover version is nil")
+	}
+	if failoverVersion.IsInt64() {
+		return m.clusterNameForFailoverVersion(failoverVersion.Int64())
+	}
+
+	residue := new(big.Int).Rem(failoverVersion, m.FailoverVersionIncrementBig()).Int64()
+	clusterName, ok := m.getTopology().versionToClusterName[residue]
+	if !ok {
+		return "", fmt.Errorf(
+			"unknown initial failover version %v of failover version %v with failover version increment %v",
+			residue,
+			failoverVersion,
+			m.failoverVersionIncrement,
+		)
+	}
+	return clusterName, nil
+}
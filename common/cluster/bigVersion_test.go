@@ -0,0 +1,59 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common"
+)
+
+func TestMetadataFailoverVersionBig(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.Equal(t, big.NewInt(TestFailoverVersionIncrement), metadata.FailoverVersionIncrementBig())
+
+	for _, version := range []int64{0, 1, 2, 20, 21, 1002, math.MaxInt64 - 7, common.EmptyVersion} {
+		clusterName, err := metadata.ClusterNameForFailoverVersionBig(big.NewInt(version))
+		require.NoError(t, err, "version: %v", version)
+		assert.Equal(t, metadata.ClusterNameForFailoverVersion(version), clusterName, "version: %v", version)
+		assert.Equal(t, version/TestFailoverVersionIncrement, metadata.FailoverGenerationBig(big.NewInt(version)).Int64())
+	}
+
+	_, err := metadata.ClusterNameForFailoverVersionBig(big.NewInt(25))
+	assert.Error(t, err)
+	_, err = metadata.ClusterNameForFailoverVersionBig(nil)
+	assert.EqualError(t, err, "failover version is nil")
+
+	// beyond the int64 range
+	version := new(big.Int).Add(new(big.Int).SetUint64(math.MaxUint64), big.NewInt(7)) // 18446744073709551622
+	clusterName, err := metadata.ClusterNameForFailoverVersionBig(version)
+	require.NoError(t, err)
+	assert.Equal(t, TestDisabledClusterName, clusterName)
+	assert.Equal(t, "1844674407370955162", metadata.FailoverGenerationBig(version).String())
+
+	_, err = metadata.ClusterNameForFailoverVersionBig(new(big.Int).Add(version, big.NewInt(3)))
+	assert.EqualError(t, err, "unknown initial failover version 5 of failover version 18446744073709551625 with failover version increment 10")
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetClusterCircuitOpen opens or closes the circuit of a cluster, the change is visible to all copies of the Metadata.
+// Unlike disabling a cluster, opening its circuit is a transient health signal: the cluster is kept in the
+// cluster info maps but skipped by GetRemoteClusterNames and NearestRemoteCluster until its circuit is closed.
+func (m Metadata) SetClusterCircuitOpen(clusterName string, open bool) {
+	m.topology.Lock()
+	defer m.topology.Unlock()
+
+	if !open {
+		delete(m.topology.openCircuits, clusterName)
+		return
+	}
+	if m.topology.openCircuits == nil {
+		m.topology.openCircuits = make(map[string]struct{})
+	}
+	m.topology.openCircuits[clusterName] = struct{}{}
+}
+
+// IsClusterCircuitOpen return whether the circuit of the cluster is open
+func (m Metadata) IsClusterCircuitOpen(clusterName string) bool {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+	_, ok := m.topology.openCircuits[clusterName]
+	return ok
+}
+
+// SetReplicationPaused pauses or resumes replication to all remote clusters, the change is visible to all copies
+// of the Metadata. Like opening circuits, it is a transient override: the cluster info maps are kept
+// but GetRemoteClusterNames and NearestRemoteCluster return no cluster while replication is paused.
+func (m Metadata) SetReplicationPaused(paused bool) {
+	m.topology.Lock()
+	defer m.topology.Unlock()
+	m.topology.replicationPaused = paused
+}
+
+// IsReplicationEnabled return whether replication is not paused and there is an enabled remote cluster
+func (m Metadata) IsReplicationEnabled() bool {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+	return !m.topology.replicationPaused && len(m.topology.current.getRemoteClusters()) > 0
+}
+
+// GetRemoteClusterNames return the sorted names of the enabled remote clusters whose circuit is not open,
+// no cluster is returned while replication is paused
+func (m Metadata) GetRemoteClusterNames() []string {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+
+	if m.topology.replicationPaused {
+		return []string{}
+	}
+	remoteClusters := m.topology.current.getRemoteClusters()
+	clusterNames := make([]string, 0, len(remoteClusters))
+	for clusterName := range remoteClusters {
+		if _, ok := m.topology.openCircuits[clusterName]; !ok {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	sort.Strings(clusterNames)
+	return clusterNames
+}
+
+// IsDomainActiveClusterReachable return whether the active cluster of a domain can serve requests from here,
+// i.e. it is enabled, its circuit is not open and it passes the health checker, if any
+func (m Metadata) IsDomainActiveClusterReachable(activeCluster string) (bool, error) {
+	m.topology.RLock()
+	info, ok := m.topology.current.allClusters[activeCluster]
+	_, circuitOpen := m.topology.openCircuits[activeCluster]
+	m.topology.RUnlock()
+
+	if !ok {
+		return false, fmt.Errorf("unknown cluster name: %v", activeCluster)
+	}
+	if !info.Enabled || circuitOpen {
+		return false, nil
+	}
+	if m.healthChecker != nil {
+		return m.healthChecker(activeCluster), nil
+	}
+	return true, nil
+}
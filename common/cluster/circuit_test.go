@@ -0,0 +1,128 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataClusterCircuit(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":  {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "us-east"}},
+		"east":   {Enabled: true, InitialFailoverVersion: 1, Tags: map[string]string{RegionTag: "us-east"}},
+		"west":   {Enabled: true, InitialFailoverVersion: 2, Tags: map[string]string{RegionTag: "us-west"}},
+		"remote": {Enabled: false, InitialFailoverVersion: 3, Tags: map[string]string{RegionTag: "us-east"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "local", "local", clusterGroup)
+	// copies observe the circuit state as well
+	metadataCopy := metadata
+
+	assert.Equal(t, []string{"east", "west"}, metadata.GetRemoteClusterNames())
+	nearest, ok := metadata.NearestRemoteCluster("us-east")
+	assert.True(t, ok)
+	assert.Equal(t, "east", nearest)
+
+	metadata.SetClusterCircuitOpen("east", true)
+	assert.True(t, metadataCopy.IsClusterCircuitOpen("east"))
+	assert.Equal(t, []string{"west"}, metadataCopy.GetRemoteClusterNames())
+	nearest, ok = metadataCopy.NearestRemoteCluster("us-east")
+	assert.True(t, ok)
+	assert.Equal(t, "west", nearest)
+	assert.Contains(t, metadataCopy.GetRemoteClusterInfo(), "east")
+
+	metadata.SetClusterCircuitOpen("west", true)
+	_, ok = metadataCopy.NearestRemoteCluster("us-east")
+	assert.False(t, ok)
+
+	metadata.SetClusterCircuitOpen("east", false)
+	assert.False(t, metadataCopy.IsClusterCircuitOpen("east"))
+	assert.Equal(t, []string{"east"}, metadataCopy.GetRemoteClusterNames())
+	nearest, ok = metadataCopy.NearestRemoteCluster("us-east")
+	assert.True(t, ok)
+	assert.Equal(t, "east", nearest)
+}
+
+func TestMetadataReplicationPaused(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	// copies observe the paused state as well
+	metadataCopy := metadata
+	assert.True(t, metadata.IsReplicationEnabled())
+
+	metadata.SetReplicationPaused(true)
+	assert.False(t, metadataCopy.IsReplicationEnabled())
+	assert.Empty(t, metadataCopy.GetRemoteClusterNames())
+	_, ok := metadataCopy.NearestRemoteCluster("")
+	assert.False(t, ok)
+	assert.Len(t, metadataCopy.GetRemoteClusterInfo(), 1)
+	assert.Equal(t, TestAllClusterInfo, metadataCopy.GetAllClusterInfo())
+
+	metadata.SetReplicationPaused(false)
+	assert.True(t, metadataCopy.IsReplicationEnabled())
+	assert.Equal(t, []string{TestAlternativeClusterName}, metadataCopy.GetRemoteClusterNames())
+	nearest, ok := metadataCopy.NearestRemoteCluster("")
+	assert.True(t, ok)
+	assert.Equal(t, TestAlternativeClusterName, nearest)
+
+	singleCluster := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestSingleDCClusterInfo)
+	assert.False(t, singleCluster.IsReplicationEnabled())
+}
+
+func TestMetadataIsDomainActiveClusterReachable(t *testing.T) {
+	unhealthy := map[string]bool{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithClusterHealthChecker(func(clusterName string) bool {
+			return !unhealthy[clusterName]
+		}),
+	)
+
+	reachable, err := metadata.IsDomainActiveClusterReachable(TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.True(t, reachable)
+	reachable, err = metadata.IsDomainActiveClusterReachable(TestCurrentClusterName)
+	assert.NoError(t, err)
+	assert.True(t, reachable)
+
+	reachable, err = metadata.IsDomainActiveClusterReachable(TestDisabledClusterName)
+	assert.NoError(t, err)
+	assert.False(t, reachable)
+
+	metadata.SetClusterCircuitOpen(TestAlternativeClusterName, true)
+	reachable, err = metadata.IsDomainActiveClusterReachable(TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.False(t, reachable)
+	metadata.SetClusterCircuitOpen(TestAlternativeClusterName, false)
+
+	unhealthy[TestAlternativeClusterName] = true
+	reachable, err = metadata.IsDomainActiveClusterReachable(TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.False(t, reachable)
+
+	_, err = metadata.IsDomainActiveClusterReachable("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package clustertest provides helpers to set up cluster groups in tests
+package clustertest
+
+import (
+	"fmt"
+
+	"github.com/uber/cadence/common/cluster"
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/service"
+)
+
+const (
+	// DefaultFailoverVersionIncrement is the failover version increment of groups built by GroupBuilder
+	DefaultFailoverVersionIncrement = int64(10)
+
+	defaultRPCTransport = "grpc"
+	defaultBasePort     = 7104
+)
+
+type (
+	// GroupBuilder builds cluster groups and their Metadata from fluent cluster definitions.
+	// The first added cluster is the primary and current cluster unless other clusters are marked so,
+	// clusters are enabled and use the order they are added in as initial failover version by default.
+	GroupBuilder struct {
+		failoverVersionIncrement int64
+		primaryClusterName       string
+		currentClusterName       string
+		clusterNames             []string
+		clusters                 map[string]*config.ClusterInformation
+	}
+
+	// ClusterBuilder defines a single cluster added to a GroupBuilder,
+	// the GroupBuilder methods can be chained to continue with the group
+	ClusterBuilder struct {
+		*GroupBuilder
+		name string
+	}
+)
+
+// NewGroupBuilder creates a GroupBuilder of an empty cluster group
+func NewGroupBuilder() *GroupBuilder {
+	return &GroupBuilder{
+		failoverVersionIncrement: DefaultFailoverVersionIncrement,
+		clusters:                 make(map[string]*config.ClusterInformation),
+	}
+}
+
+// FailoverVersionIncrement sets the failover version increment of the group
+func (b *GroupBuilder) FailoverVersionIncrement(increment int64) *GroupBuilder {
+	b.failoverVersionIncrement = increment
+	return b
+}
+
+// AddCluster adds a cluster to the group, or continues the definition of the cluster if it was already added
+func (b *GroupBuilder) AddCluster(name string) *ClusterBuilder {
+	if _, ok := b.clusters[name]; !ok {
+		index := len(b.clusterNames)
+		b.clusterNames = append(b.clusterNames, name)
+		b.clusters[name] = &config.ClusterInformation{
+			Enabled:                true,
+			InitialFailoverVersion: int64(index),
+			RPCName:                service.Frontend,
+			RPCAddress:             fmt.Sprintf("127.0.0.1:%v", defaultBasePort+1000*index),
+			RPCTransport:           defaultRPCTransport,
+		}
+	}
+	return &ClusterBuilder{GroupBuilder: b, name: name}
+}
+
+// ClusterGroup returns the cluster group built so far
+func (b *GroupBuilder) ClusterGroup() map[string]config.ClusterInformation {
+	clusterGroup := make(map[string]config.ClusterInformation, len(b.clusters))
+	for name, info := range b.clusters {
+		clusterGroup[name] = *info
+	}
+	return clusterGroup
+}
+
+// PrimaryClusterName returns the name of the primary cluster of the group
+func (b *GroupBuilder) PrimaryClusterName() string {
+	if b.primaryClusterName == "" && len(b.clusterNames) > 0 {
+		return b.clusterNames[0]
+	}
+	return b.primaryClusterName
+}
+
+// CurrentClusterName returns the name of the current cluster of the group
+func (b *GroupBuilder) CurrentClusterName() string {
+	if b.currentClusterName == "" && len(b.clusterNames) > 0 {
+		return b.clusterNames[0]
+	}
+	return b.currentClusterName
+}
+
+// Metadata returns the Metadata of the cluster group built so far
+func (b *GroupBuilder) Metadata(opts ...cluster.Option) cluster.Metadata {
+	return cluster.NewMetadata(
+		b.failoverVersionIncrement,
+		b.PrimaryClusterName(),
+		b.CurrentClusterName(),
+		b.ClusterGroup(),
+		opts...,
+	)
+}
+
+// Primary makes the cluster the primary cluster of the group
+func (b *ClusterBuilder) Primary() *ClusterBuilder {
+	b.primaryClusterName = b.name
+	return b
+}
+
+// Current makes the cluster the current cluster of the group
+func (b *ClusterBuilder) Current() *ClusterBuilder {
+	b.currentClusterName = b.name
+	return b
+}
+
+// Enabled sets whether the cluster is enabled
+func (b *ClusterBuilder) Enabled(enabled bool) *ClusterBuilder {
+	b.clusters[b.name].Enabled = enabled
+	return b
+}
+
+// InitialVersion sets the initial failover version of the cluster
+func (b *ClusterBuilder) InitialVersion(initialFailoverVersion int64) *ClusterBuilder {
+	b.clusters[b.name].InitialFailoverVersion = initialFailoverVersion
+	return b
+}
+
+// RPCAddress sets the RPC address of the cluster
+func (b *ClusterBuilder) RPCAddress(address string) *ClusterBuilder {
+	b.clusters[b.name].RPCAddress = address
+	return b
+}
+
+// Info modifies the cluster information of the cluster, for fields without dedicated method
+func (b *ClusterBuilder) Info(modify func(info *config.ClusterInformation)) *ClusterBuilder {
+	modify(b.clusters[b.name])
+	return b
+}
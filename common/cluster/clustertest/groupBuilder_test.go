@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clustertest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/cluster"
+	"github.com/uber/cadence/common/config"
+)
+
+func TestGroupBuilder(t *testing.T) {
+	builder := NewGroupBuilder().
+		AddCluster("a").
+		AddCluster("b").Primary().Current().InitialVersion(5).
+		AddCluster("c").Enabled(false).Info(func(info *config.ClusterInformation) {
+		info.Tags = map[string]string{cluster.RegionTag: "region"}
+	})
+
+	clusterGroup := builder.ClusterGroup()
+	require.Len(t, clusterGroup, 3)
+	assert.Equal(t, config.ClusterInformation{
+		Enabled:                true,
+		InitialFailoverVersion: 0,
+		RPCName:                "cadence-frontend",
+		RPCAddress:             "127.0.0.1:7104",
+		RPCTransport:           "grpc",
+	}, clusterGroup["a"])
+	assert.Equal(t, int64(5), clusterGroup["b"].InitialFailoverVersion)
+	assert.Equal(t, "127.0.0.1:8104", clusterGroup["b"].RPCAddress)
+	assert.False(t, clusterGroup["c"].Enabled)
+	assert.Equal(t, int64(2), clusterGroup["c"].InitialFailoverVersion)
+	assert.Equal(t, "region", clusterGroup["c"].Tags[cluster.RegionTag])
+	assert.Equal(t, "b", builder.PrimaryClusterName())
+	assert.Equal(t, "b", builder.CurrentClusterName())
+
+	// the built group is a copy
+	clusterGroup["a"] = config.ClusterInformation{}
+	assert.True(t, builder.ClusterGroup()["a"].Enabled)
+
+	metadata := builder.Metadata()
+	assert.True(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, "b", metadata.GetCurrentClusterName())
+	assert.Equal(t, builder.ClusterGroup(), metadata.GetAllClusterInfo())
+	assert.Len(t, metadata.GetEnabledClusterInfo(), 2)
+}
+
+func TestGroupBuilderDefaults(t *testing.T) {
+	builder := NewGroupBuilder().FailoverVersionIncrement(100).AddCluster("a").AddCluster("b").AddCluster("a").GroupBuilder
+	assert.Equal(t, "a", builder.PrimaryClusterName())
+	assert.Equal(t, "a", builder.CurrentClusterName())
+	assert.Len(t, builder.ClusterGroup(), 2)
+	assert.Equal(t, int64(101), builder.Metadata().GetNextFailoverVersion("b", 2))
+
+	assert.Empty(t, NewGroupBuilder().PrimaryClusterName())
+}
+
+func TestTwoClusterFailover(t *testing.T) {
+	builder := NewGroupBuilder().
+		AddCluster("active").Primary().
+		AddCluster("standby").Current()
+	metadata := builder.Metadata()
+	assert.False(t, metadata.IsPrimaryCluster())
+
+	// a domain active in the primary cluster fails over to the current cluster
+	domainVersion := metadata.GetNextFailoverVersion("active", 21)
+	assert.Equal(t, int64(30), domainVersion)
+	assert.Equal(t, "active", metadata.ClusterNameForFailoverVersion(domainVersion))
+	failoverVersion := metadata.GetNextFailoverVersion("standby", domainVersion)
+	assert.Equal(t, int64(31), failoverVersion)
+	assert.Equal(t, "standby", metadata.ClusterNameForFailoverVersion(failoverVersion))
+	assert.False(t, metadata.IsVersionFromSameCluster(domainVersion, failoverVersion))
+
+	// the current cluster takes over as primary
+	require.NoError(t, metadata.PromotePrimary("standby"))
+	assert.True(t, metadata.IsPrimaryCluster())
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/config"
+)
+
+const (
+	// EnvFailoverVersionIncrement is the env var holding the failover version increment
+	EnvFailoverVersionIncrement = "CADENCE_FAILOVER_VERSION_INCREMENT"
+	// EnvPrimaryClusterName is the env var holding the primary cluster name
+	EnvPrimaryClusterName = "CADENCE_PRIMARY_CLUSTER"
+	// EnvCurrentClusterName is the env var holding the current cluster name
+	EnvCurrentClusterName = "CADENCE_CURRENT_CLUSTER"
+	// EnvClusterPrefix is the prefix of the env vars holding the cluster information,
+	// CADENCE_CLUSTER_<NAME>_<SETTING>
+	EnvClusterPrefix = "CADENCE_CLUSTER_"
+
+	envClusterEnabled        = "_ENABLED"
+	envClusterInitialVersion = "_INITIAL_VERSION"
+	envClusterRPCName        = "_RPC_NAME"
+	envClusterRPCAddress     = "_RPC_ADDRESS"
+	envClusterRPCTransport   = "_RPC_TRANSPORT"
+)
+
+type (
+	// EnvClusterGroupProvider is a PersistedClusterStore reading the cluster group config from env vars,
+	// e.g. for containerized deployments without mounted config files. The schema is:
+	//
+	//	CADENCE_FAILOVER_VERSION_INCREMENT=10
+	//	CADENCE_PRIMARY_CLUSTER=us-west
+	//	CADENCE_CURRENT_CLUSTER=us-east
+	//	CADENCE_CLUSTER_<NAME>_ENABLED=true
+	//	CADENCE_CLUSTER_<NAME>_INITIAL_VERSION=1
+	//	CADENCE_CLUSTER_<NAME>_RPC_NAME=cadence-frontend
+	//	CADENCE_CLUSTER_<NAME>_RPC_ADDRESS=127.0.0.1:7933
+	//	CADENCE_CLUSTER_<NAME>_RPC_TRANSPORT=grpc
+	//
+	// where <NAME> is the cluster name in upper case with dashes replaced by underscores, e.g. US_WEST for us-west.
+	// The mapping is lossy: <NAME> is lower cased and underscores become dashes, so cluster names with upper case
+	// letters or underscores cannot be expressed. The primary and current cluster names must be the mapped names.
+	// RPC name and transport default as for config files and the cluster group config is validated.
+	EnvClusterGroupProvider struct {
+		environ func() []string
+	}
+)
+
+var _ PersistedClusterStore = (*EnvClusterGroupProvider)(nil)
+
+// NewEnvClusterGroupProvider creates a new EnvClusterGroupProvider reading the env vars of the process
+func NewEnvClusterGroupProvider() *EnvClusterGroupProvider {
+	return &EnvClusterGroupProvider{environ: os.Environ}
+}
+
+// GetClusterGroupMetadata parses the cluster group config from the env vars,
+// it fails for malformed values, unknown cluster settings and invalid cluster group configs.
+// The env vars are read without blocking, ctx is unused.
+func (p *EnvClusterGroupProvider) GetClusterGroupMetadata(ctx context.Context) (*config.ClusterGroupMetadata, error) {
+	clusterGroupMetadata := &config.ClusterGroupMetadata{
+		ClusterGroup: make(map[string]config.ClusterInformation),
+	}
+
+	var errs error
+	environ := p.environ()
+	sort.Strings(environ)
+	for _, env := range environ {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], parts[1]
+		switch {
+		case key == EnvFailoverVersionIncrement:
+			increment, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("%v: invalid integer %q", key, value))
+				continue
+			}
+			clusterGroupMetadata.FailoverVersionIncrement = increment
+		case key == EnvPrimaryClusterName:
+			clusterGroupMetadata.PrimaryClusterName = value
+		case key == EnvCurrentClusterName:
+			clusterGroupMetadata.CurrentClusterName = value
+		case strings.HasPrefix(key, EnvClusterPrefix):
+			errs = multierr.Append(errs, parseEnvClusterSetting(clusterGroupMetadata.ClusterGroup, key, value))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	clusterGroupMetadata.FillDefaults()
+	if err := clusterGroupMetadata.Validate(); err != nil {
+		return nil, err
+	}
+	return clusterGroupMetadata, nil
+}
+
+func parseEnvClusterSetting(clusterGroup map[string]config.ClusterInformation, key string, value string) error {
+	setting := strings.TrimPrefix(key, EnvClusterPrefix)
+	for _, suffix := range []string{
+		envClusterInitialVersion,
+		envClusterRPCTransport,
+		envClusterRPCAddress,
+		envClusterRPCName,
+		envClusterEnabled,
+	} {
+		if !strings.HasSuffix(setting, suffix) || len(setting) == len(suffix) {
+			continue
+		}
+
+		clusterName := strings.ReplaceAll(strings.ToLower(strings.TrimSuffix(setting, suffix)), "_", "-")
+		info := clusterGroup[clusterName]
+		switch suffix {
+		case envClusterEnabled:
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%v: invalid bool %q", key, value)
+			}
+			info.Enabled = enabled
+		case envClusterInitialVersion:
+			initialFailoverVersion, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%v: invalid integer %q", key, value)
+			}
+			info.InitialFailoverVersion = initialFailoverVersion
+		case envClusterRPCName:
+			info.RPCName = value
+		case envClusterRPCAddress:
+			info.RPCAddress = value
+		case envClusterRPCTransport:
+			info.RPCTransport = value
+		}
+		clusterGroup[clusterName] = info
+		return nil
+	}
+	return fmt.Errorf("%v: unknown cluster setting", key)
+}
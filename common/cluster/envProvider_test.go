@@ -0,0 +1,142 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func newTestEnvClusterGroupProvider(environ ...string) *EnvClusterGroupProvider {
+	return &EnvClusterGroupProvider{environ: func() []string { return environ }}
+}
+
+func TestEnvClusterGroupProvider(t *testing.T) {
+	provider := newTestEnvClusterGroupProvider(
+		"HOME=/root",
+		"CADENCE_FAILOVER_VERSION_INCREMENT=10",
+		"CADENCE_PRIMARY_CLUSTER=us-west",
+		"CADENCE_CURRENT_CLUSTER=us-east",
+		"CADENCE_CLUSTER_US_WEST_ENABLED=true",
+		"CADENCE_CLUSTER_US_WEST_INITIAL_VERSION=1",
+		"CADENCE_CLUSTER_US_WEST_RPC_ADDRESS=127.0.0.1:7933",
+		"CADENCE_CLUSTER_US_WEST_RPC_TRANSPORT=grpc",
+		"CADENCE_CLUSTER_US_EAST_ENABLED=true",
+		"CADENCE_CLUSTER_US_EAST_INITIAL_VERSION=0",
+		"CADENCE_CLUSTER_US_EAST_RPC_NAME=frontend",
+		"CADENCE_CLUSTER_US_EAST_RPC_ADDRESS=127.0.0.1:8933",
+		"CADENCE_CLUSTER_LEGACY_INITIAL_VERSION=2",
+	)
+
+	clusterGroupMetadata, err := provider.GetClusterGroupMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &config.ClusterGroupMetadata{
+		FailoverVersionIncrement: 10,
+		PrimaryClusterName:       "us-west",
+		CurrentClusterName:       "us-east",
+		ClusterGroup: map[string]config.ClusterInformation{
+			"us-west": {
+				Enabled:                true,
+				InitialFailoverVersion: 1,
+				RPCName:                "cadence-frontend",
+				RPCAddress:             "127.0.0.1:7933",
+				RPCTransport:           "grpc",
+			},
+			"us-east": {
+				Enabled:                true,
+				InitialFailoverVersion: 0,
+				RPCName:                "frontend",
+				RPCAddress:             "127.0.0.1:8933",
+				RPCTransport:           "tchannel",
+			},
+			"legacy": {
+				Enabled:                false,
+				InitialFailoverVersion: 2,
+				RPCName:                "cadence-frontend",
+				RPCTransport:           "tchannel",
+			},
+		},
+	}, clusterGroupMetadata)
+
+	metadata, err := NewPersistentMetadata(context.Background(), provider)
+	require.NoError(t, err)
+	assert.False(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, []string{"us-west"}, metadata.GetRemoteClusterNames())
+}
+
+func TestEnvClusterGroupProviderErrors(t *testing.T) {
+	tests := []struct {
+		msg     string
+		environ []string
+		err     string
+	}{
+		{
+			msg: "malformed values",
+			environ: []string{
+				"CADENCE_FAILOVER_VERSION_INCREMENT=ten",
+				"CADENCE_CLUSTER_A_ENABLED=yes please",
+				"CADENCE_CLUSTER_A_INITIAL_VERSION=1.5",
+			},
+			err: `CADENCE_CLUSTER_A_ENABLED: invalid bool "yes please"; ` +
+				`CADENCE_CLUSTER_A_INITIAL_VERSION: invalid integer "1.5"; ` +
+				`CADENCE_FAILOVER_VERSION_INCREMENT: invalid integer "ten"`,
+		},
+		{
+			msg:     "unknown cluster setting",
+			environ: []string{"CADENCE_CLUSTER_A_REGION=us-west", "CADENCE_CLUSTER__ENABLED=true"},
+			err:     "CADENCE_CLUSTER_A_REGION: unknown cluster setting",
+		},
+		{
+			msg: "invalid cluster group",
+			environ: []string{
+				"CADENCE_FAILOVER_VERSION_INCREMENT=10",
+				"CADENCE_PRIMARY_CLUSTER=a",
+				"CADENCE_CURRENT_CLUSTER=b",
+				"CADENCE_CLUSTER_A_ENABLED=true",
+				"CADENCE_CLUSTER_A_RPC_ADDRESS=127.0.0.1:7933",
+			},
+			err: "current cluster is not specified in the cluster group",
+		},
+		{
+			msg: "unmapped cluster name",
+			environ: []string{
+				"CADENCE_FAILOVER_VERSION_INCREMENT=10",
+				"CADENCE_PRIMARY_CLUSTER=US_WEST",
+				"CADENCE_CURRENT_CLUSTER=US_WEST",
+				"CADENCE_CLUSTER_US_WEST_ENABLED=true",
+				"CADENCE_CLUSTER_US_WEST_RPC_ADDRESS=127.0.0.1:7933",
+			},
+			err: "primary cluster is not specified in the cluster group",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := newTestEnvClusterGroupProvider(tt.environ...).GetClusterGroupMetadata(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// GetClustersInEnvironment return the sorted names of the clusters in the environment
+func (m Metadata) GetClustersInEnvironment(environment string) []string {
+	clusters := make(map[string]config.ClusterInformation)
+	for clusterName, info := range m.getTopology().allClusters {
+		if info.Environment == environment {
+			clusters[clusterName] = info
+		}
+	}
+	return sortedClusterNames(clusters)
+}
+
+// ValidateReplicationEnvironments is a MetadataValidator rejecting enabled remote clusters the current cluster
+// replicates to in another environment than the current cluster, e.g. to prevent staging to production leaks.
+// Register it with RegisterMetadataValidator to enforce it.
+func ValidateReplicationEnvironments(m Metadata) error {
+	topology := m.getTopology()
+	currentInfo, ok := topology.allClusters[m.currentClusterName]
+	if !ok {
+		return nil
+	}
+
+	var errs error
+	remoteClusters := topology.getRemoteClusters()
+	for _, clusterName := range sortedClusterNames(remoteClusters) {
+		info := remoteClusters[clusterName]
+		if replicatesTo(currentInfo, clusterName) && info.Environment != currentInfo.Environment {
+			errs = multierr.Append(errs, fmt.Errorf(
+				"cluster %v: replication target %v is in environment %q, not %q",
+				m.currentClusterName,
+				clusterName,
+				info.Environment,
+				currentInfo.Environment,
+			))
+		}
+	}
+	return errs
+}
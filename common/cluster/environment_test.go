@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataGetClustersInEnvironment(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"prod-east":    {Enabled: true, InitialFailoverVersion: 0, Environment: "production"},
+		"prod-west":    {Enabled: false, InitialFailoverVersion: 1, Environment: "production"},
+		"staging-east": {Enabled: true, InitialFailoverVersion: 2, Environment: "staging"},
+		"unlabeled":    {Enabled: true, InitialFailoverVersion: 3},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "prod-east", "prod-east", clusterGroup)
+
+	assert.Equal(t, []string{"prod-east", "prod-west"}, metadata.GetClustersInEnvironment("production"))
+	assert.Equal(t, []string{"staging-east"}, metadata.GetClustersInEnvironment("staging"))
+	assert.Equal(t, []string{"unlabeled"}, metadata.GetClustersInEnvironment(""))
+	assert.Empty(t, metadata.GetClustersInEnvironment("unknown"))
+}
+
+func TestValidateReplicationEnvironments(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"prod-east":    {Enabled: true, InitialFailoverVersion: 0, Environment: "production"},
+		"prod-west":    {Enabled: true, InitialFailoverVersion: 1, Environment: "production"},
+		"staging-east": {Enabled: true, InitialFailoverVersion: 2, Environment: "staging"},
+		"staging-west": {Enabled: false, InitialFailoverVersion: 3, Environment: "staging"},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "prod-east", "prod-east", clusterGroup)
+	assert.EqualError(t,
+		ValidateReplicationEnvironments(metadata),
+		`cluster prod-east: replication target staging-east is in environment "staging", not "production"`,
+	)
+
+	// replicating within the environment only
+	prodEast := clusterGroup["prod-east"]
+	prodEast.ReplicaClusters = []string{"prod-west"}
+	clusterGroup["prod-east"] = prodEast
+	metadata = NewMetadata(TestFailoverVersionIncrement, "prod-east", "prod-east", clusterGroup)
+	assert.NoError(t, ValidateReplicationEnvironments(metadata))
+
+	assert.NoError(t, ValidateReplicationEnvironments(GetTestClusterMetadata(true)))
+}
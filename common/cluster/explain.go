@@ -0,0 +1,80 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/cadence/common"
+)
+
+// ExplainFailoverVersion return a human readable explanation of how the failover version resolves to a cluster,
+// e.g. "version 205 -> generation 2, residue 5 -> cluster 'us-west' (enabled, remote)", for support investigations.
+// Versions not belonging to any cluster are explained as well instead of panicking.
+func (m Metadata) ExplainFailoverVersion(version int64) string {
+	if version == common.EmptyVersion {
+		return fmt.Sprintf("version %v -> empty version -> cluster '%v' (current)", version, m.currentClusterName)
+	}
+
+	resolution := fmt.Sprintf(
+		"version %v -> generation %v, residue %v",
+		version,
+		version/m.failoverVersionIncrement,
+		version%m.failoverVersionIncrement,
+	)
+	if m.IsReservedTestVersion(version) {
+		return fmt.Sprintf("%v -> reserved test cluster '%v'", resolution, ReservedTestCluster)
+	}
+	clusterName, err := m.clusterNameForFailoverVersion(version)
+	if err != nil {
+		return fmt.Sprintf("%v -> no cluster with initial failover version %v", resolution, version%m.failoverVersionIncrement)
+	}
+
+	topology := m.getTopology()
+	info := topology.allClusters[clusterName]
+	if info.SourceIncrement > 0 && version%m.failoverVersionIncrement != info.InitialFailoverVersion {
+		resolution = fmt.Sprintf(
+			"version %v -> source increment %v, generation %v, residue %v",
+			version,
+			info.SourceIncrement,
+			version/info.SourceIncrement,
+			version%info.SourceIncrement,
+		)
+	}
+
+	attributes := []string{"enabled"}
+	if !info.Enabled {
+		attributes[0] = "disabled"
+	}
+	if clusterName == m.currentClusterName {
+		attributes = append(attributes, "current")
+	} else {
+		attributes = append(attributes, "remote")
+	}
+	if clusterName == topology.primaryClusterName {
+		attributes = append(attributes, "primary")
+	}
+	if info.ArchivalOnly {
+		attributes = append(attributes, "archival only")
+	}
+	return fmt.Sprintf("%v -> cluster '%v' (%v)", resolution, clusterName, strings.Join(attributes, ", "))
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common"
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataExplainFailoverVersion(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithReservedTestResidues(9),
+	)
+
+	tests := map[int64]string{
+		20:                  "version 20 -> generation 2, residue 0 -> cluster 'active' (enabled, current, primary)",
+		21:                  "version 21 -> generation 2, residue 1 -> cluster 'standby' (enabled, remote)",
+		32:                  "version 32 -> generation 3, residue 2 -> cluster 'disabled' (disabled, remote)",
+		25:                  "version 25 -> generation 2, residue 5 -> no cluster with initial failover version 5",
+		29:                  "version 29 -> generation 2, residue 9 -> reserved test cluster 'reserved-test-cluster'",
+		common.EmptyVersion: "version -24 -> empty version -> cluster 'active' (current)",
+	}
+	for version, explanation := range tests {
+		assert.Equal(t, explanation, metadata.ExplainFailoverVersion(version))
+	}
+}
+
+func TestMetadataExplainFailoverVersionSourceIncrement(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":    {Enabled: true, InitialFailoverVersion: 0},
+		"migrated": {Enabled: true, InitialFailoverVersion: 3, SourceIncrement: 10, ArchivalOnly: true},
+	}
+	metadata := NewMetadata(100, "local", "local", clusterGroup)
+
+	assert.Equal(t,
+		"version 23 -> source increment 10, generation 2, residue 3 -> cluster 'migrated' (enabled, remote, archival only)",
+		metadata.ExplainFailoverVersion(23),
+	)
+	assert.Equal(t,
+		"version 103 -> generation 1, residue 3 -> cluster 'migrated' (enabled, remote, archival only)",
+		metadata.ExplainFailoverVersion(103),
+	)
+}
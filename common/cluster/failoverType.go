@@ -0,0 +1,85 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import "fmt"
+
+const (
+	// FailoverTypeAutomatic indicates a failover initiated by the system, e.g. on cluster outage
+	FailoverTypeAutomatic FailoverType = iota
+	// FailoverTypeManual indicates a failover initiated by an operator
+	FailoverTypeManual
+)
+
+type (
+	// FailoverType is the type of a failover, encoded in the failover versions by GetNextFailoverVersionForType
+	FailoverType int
+)
+
+// String returns the name of the failover type
+func (t FailoverType) String() string {
+	switch t {
+	case FailoverTypeAutomatic:
+		return "Automatic"
+	case FailoverTypeManual:
+		return "Manual"
+	default:
+		return fmt.Sprintf("FailoverType(%d)", int(t))
+	}
+}
+
+// GetNextFailoverVersionForType return the next failover version of the cluster for a failover of the given type.
+// The type is encoded in the parity of the generation, as all versions of a generation besides the cluster's own
+// belong to other clusters: automatic failovers use even generations and manual failovers odd generations.
+// ManualFailoverVersion uses the same encoding. The returned version is the smallest version greater than or equal
+// to the current failover version with the cluster's residue and a generation of the right parity, so each failover
+// may skip one generation, see FailoverTypeOfVersion.
+func (m Metadata) GetNextFailoverVersionForType(
+	cluster string,
+	currentFailoverVersion int64,
+	failoverType FailoverType,
+) (int64, error) {
+	if failoverType != FailoverTypeAutomatic && failoverType != FailoverTypeManual {
+		return 0, fmt.Errorf("unknown failover type: %v", failoverType)
+	}
+	failoverVersion, err := m.GetNextFailoverVersionSafe(cluster, currentFailoverVersion)
+	if err != nil {
+		return 0, err
+	}
+	if m.failoverTypeOfGeneration(failoverVersion/m.failoverVersionIncrement) != failoverType {
+		return m.GetNextFailoverVersionSafe(cluster, failoverVersion+1)
+	}
+	return failoverVersion, nil
+}
+
+// FailoverTypeOfVersion return the failover type encoded in the failover version by GetNextFailoverVersionForType
+// or ManualFailoverVersion. Versions from GetNextFailoverVersion do not encode a type, the result is meaningless
+// for them, e.g. for versions of domains failed over before the encoding was adopted.
+func (m Metadata) FailoverTypeOfVersion(failoverVersion int64) FailoverType {
+	return m.failoverTypeOfGeneration(failoverVersion / m.failoverVersionIncrement)
+}
+
+func (m Metadata) failoverTypeOfGeneration(generation int64) FailoverType {
+	if generation%2 == 0 {
+		return FailoverTypeAutomatic
+	}
+	return FailoverTypeManual
+}
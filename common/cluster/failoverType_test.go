@@ -0,0 +1,57 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataGetNextFailoverVersionForType(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	tests := []struct {
+		currentVersion int64
+		failoverType   FailoverType
+		version        int64
+	}{
+		{currentVersion: 20, failoverType: FailoverTypeAutomatic, version: 21},
+		{currentVersion: 20, failoverType: FailoverTypeManual, version: 31},
+		{currentVersion: 22, failoverType: FailoverTypeAutomatic, version: 41},
+		{currentVersion: 22, failoverType: FailoverTypeManual, version: 31},
+		{currentVersion: 31, failoverType: FailoverTypeManual, version: 31},
+		{currentVersion: 0, failoverType: FailoverTypeAutomatic, version: 1},
+	}
+	for _, tt := range tests {
+		version, err := metadata.GetNextFailoverVersionForType(TestAlternativeClusterName, tt.currentVersion, tt.failoverType)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.version, version, "current version %v, failover type %v", tt.currentVersion, tt.failoverType)
+		assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(version))
+		assert.Equal(t, tt.failoverType, metadata.FailoverTypeOfVersion(version))
+	}
+
+	_, err := metadata.GetNextFailoverVersionForType(TestAlternativeClusterName, 20, FailoverType(5))
+	assert.EqualError(t, err, "unknown failover type: FailoverType(5)")
+	_, err = metadata.GetNextFailoverVersionForType("unknown", 20, FailoverTypeManual)
+	assert.Error(t, err)
+	assert.Equal(t, "Manual", FailoverTypeManual.String())
+}
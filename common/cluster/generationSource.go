@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+)
+
+type (
+	// GenerationSource provides failover generations decoupled from the current failover version,
+	// e.g. a monotonic counter stored outside of the cluster metadata
+	GenerationSource interface {
+		// Next return the next failover generation
+		Next() int64
+	}
+)
+
+// NextFailoverVersionFromSource return the failover version of the cluster at the next generation of the source,
+// i.e. generation * failoverVersionIncrement + initial failover version. It fails like GetNextFailoverVersionSafe
+// and for negative generations or generations whose failover version would overflow.
+func (m Metadata) NextFailoverVersionFromSource(cluster string, source GenerationSource) (int64, error) {
+	generation := source.Next()
+	if generation < 0 || generation > (math.MaxInt64-m.failoverVersionIncrement)/m.failoverVersionIncrement {
+		return 0, fmt.Errorf("cluster %v: invalid failover generation %v from generation source", cluster, generation)
+	}
+	return m.GetNextFailoverVersionSafe(cluster, generation*m.failoverVersionIncrement)
+}
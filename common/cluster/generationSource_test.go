@@ -0,0 +1,69 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGenerationSource struct {
+	generation int64
+}
+
+func (s *fakeGenerationSource) Next() int64 {
+	s.generation++
+	return s.generation
+}
+
+func TestMetadataNextFailoverVersionFromSource(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithMaxFailoverGeneration(3),
+	)
+	source := &fakeGenerationSource{}
+
+	version, err := metadata.NextFailoverVersionFromSource(TestAlternativeClusterName, source)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), version)
+	version, err = metadata.NextFailoverVersionFromSource(TestCurrentClusterName, source)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), version)
+	version, err = metadata.NextFailoverVersionFromSource(TestAlternativeClusterName, source)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(31), version)
+
+	_, err = metadata.NextFailoverVersionFromSource(TestAlternativeClusterName, source)
+	assert.True(t, errors.Is(err, ErrFailoverGenerationExceeded))
+	_, err = metadata.NextFailoverVersionFromSource("unknown", source)
+	assert.Error(t, err)
+
+	_, err = metadata.NextFailoverVersionFromSource(TestAlternativeClusterName, &fakeGenerationSource{generation: -2})
+	assert.EqualError(t, err, "cluster standby: invalid failover generation -1 from generation source")
+	_, err = GetTestClusterMetadata(true).NextFailoverVersionFromSource(TestAlternativeClusterName, &fakeGenerationSource{generation: math.MaxInt64 / 10})
+	assert.EqualError(t, err, "cluster standby: invalid failover generation 922337203685477581 from generation source")
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type (
+	// RemoteHandshake is the topology hash and failover version scheme reported by a remote cluster
+	RemoteHandshake struct {
+		TopologyHash string
+		SchemeID     string
+	}
+)
+
+// SchemeID return an identifier of the failover version scheme, i.e. the failover version increment and
+// the initial failover version of each cluster. Clusters with the same scheme ID resolve versions identically.
+func (m Metadata) SchemeID() string {
+	topology := m.getTopology()
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "increment=%v", m.failoverVersionIncrement)
+	for _, clusterName := range sortedClusterNames(topology.allClusters) {
+		fmt.Fprintf(&builder, ";%q=%v", clusterName, topology.allClusters[clusterName].InitialFailoverVersion)
+	}
+	return hashString(builder.String())
+}
+
+// TopologyHash return a hash of the replication topology, i.e. the clusters with their initial failover version,
+// enabled state and replica clusters. It does not depend on the current cluster or on how clusters are reached,
+// so all clusters of a well configured cluster group report the same hash.
+func (m Metadata) TopologyHash() string {
+	topology := m.getTopology()
+	var builder strings.Builder
+	for _, clusterName := range sortedClusterNames(topology.allClusters) {
+		info := topology.allClusters[clusterName]
+		fmt.Fprintf(
+			&builder,
+			"%q:%v:%v:%q;",
+			clusterName,
+			info.InitialFailoverVersion,
+			info.Enabled,
+			info.ReplicaClusters,
+		)
+	}
+	return hashString(builder.String())
+}
+
+// VerifyRemoteHandshake verifies the topology hash and scheme ID reported by a remote cluster
+// match the local ones, any mismatch is described in the returned error
+func (m Metadata) VerifyRemoteHandshake(clusterName string, remoteHash string, remoteSchemeID string) error {
+	if _, ok := m.GetAllClusterInfo()[clusterName]; !ok {
+		return fmt.Errorf("unknown cluster name: %v", clusterName)
+	}
+	if schemeID := m.SchemeID(); remoteSchemeID != schemeID {
+		return fmt.Errorf(
+			"cluster %v uses failover version scheme %v, local scheme is %v, failover versions would resolve differently",
+			clusterName,
+			remoteSchemeID,
+			schemeID,
+		)
+	}
+	if topologyHash := m.TopologyHash(); remoteHash != topologyHash {
+		return fmt.Errorf(
+			"cluster %v has topology hash %v, local topology hash is %v, cluster group configs differ",
+			clusterName,
+			remoteHash,
+			topologyHash,
+		)
+	}
+	return nil
+}
+
+// RecordRemoteHandshake verifies the topology hash and scheme ID reported by a remote cluster with
+// VerifyRemoteHandshake and records them on success, the record is visible to all copies of the Metadata
+func (m Metadata) RecordRemoteHandshake(clusterName string, remoteHash string, remoteSchemeID string) error {
+	if err := m.VerifyRemoteHandshake(clusterName, remoteHash, remoteSchemeID); err != nil {
+		return err
+	}
+
+	m.topology.Lock()
+	defer m.topology.Unlock()
+	if m.topology.remoteHandshakes == nil {
+		m.topology.remoteHandshakes = make(map[string]RemoteHandshake)
+	}
+	m.topology.remoteHandshakes[clusterName] = RemoteHandshake{TopologyHash: remoteHash, SchemeID: remoteSchemeID}
+	return nil
+}
+
+// GetRemoteHandshake return the last handshake recorded for the remote cluster
+func (m Metadata) GetRemoteHandshake(clusterName string) (RemoteHandshake, bool) {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+	handshake, ok := m.topology.remoteHandshakes[clusterName]
+	return handshake, ok
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataVerifyRemoteHandshake(t *testing.T) {
+	local := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	remote := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestAlternativeClusterName, TestAllClusterInfo)
+	assert.Equal(t, local.SchemeID(), remote.SchemeID())
+	assert.Equal(t, local.TopologyHash(), remote.TopologyHash())
+
+	assert.NoError(t, local.VerifyRemoteHandshake(TestAlternativeClusterName, remote.TopologyHash(), remote.SchemeID()))
+	_, ok := local.GetRemoteHandshake(TestAlternativeClusterName)
+	assert.False(t, ok)
+	assert.NoError(t, local.RecordRemoteHandshake(TestAlternativeClusterName, remote.TopologyHash(), remote.SchemeID()))
+	handshake, ok := local.GetRemoteHandshake(TestAlternativeClusterName)
+	assert.True(t, ok)
+	assert.Equal(t, RemoteHandshake{TopologyHash: remote.TopologyHash(), SchemeID: remote.SchemeID()}, handshake)
+
+	otherScheme := NewMetadata(TestFailoverVersionIncrement*10, TestCurrentClusterName, TestAlternativeClusterName, TestAllClusterInfo)
+	assert.Equal(t, local.TopologyHash(), otherScheme.TopologyHash())
+	err := local.VerifyRemoteHandshake(TestAlternativeClusterName, otherScheme.TopologyHash(), otherScheme.SchemeID())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover versions would resolve differently")
+
+	disabledInfo := TestAllClusterInfo[TestDisabledClusterName]
+	disabledInfo.Enabled = true
+	otherTopology := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestAlternativeClusterName, map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		TestDisabledClusterName:    disabledInfo,
+	})
+	assert.Equal(t, local.SchemeID(), otherTopology.SchemeID())
+	err = local.RecordRemoteHandshake(TestAlternativeClusterName, otherTopology.TopologyHash(), otherTopology.SchemeID())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster group configs differ")
+	handshake, _ = local.GetRemoteHandshake(TestAlternativeClusterName)
+	assert.Equal(t, remote.TopologyHash(), handshake.TopologyHash)
+
+	assert.EqualError(t, local.VerifyRemoteHandshake("unknown", local.TopologyHash(), local.SchemeID()), "unknown cluster name: unknown")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// MergeClusterGroups returns the union of two cluster groups, e.g. when federating independent deployments
+// A cluster defined in both groups must have the same config, and an initial failover version
+// must not be used by clusters with different names, all conflicts are reported in the returned error
+func MergeClusterGroups(a, b map[string]config.ClusterInformation) (map[string]config.ClusterInformation, error) {
+	merged := make(map[string]config.ClusterInformation, len(a)+len(b))
+	for name, info := range a {
+		merged[name] = info
+	}
+
+	var errs error
+	for _, name := range sortedClusterNames(b) {
+		info := b[name]
+		if existing, ok := merged[name]; ok {
+			if !reflect.DeepEqual(existing, info) {
+				errs = multierr.Append(errs, fmt.Errorf("cluster %v is defined with different config", name))
+			}
+			continue
+		}
+		merged[name] = info
+	}
+
+	versionToClusterName := make(map[int64]string, len(merged))
+	for _, name := range sortedClusterNames(merged) {
+		initialVersion := merged[name].InitialFailoverVersion
+		if other, ok := versionToClusterName[initialVersion]; ok {
+			errs = multierr.Append(errs, fmt.Errorf("clusters %v and %v have the same initial failover version %v", other, name, initialVersion))
+			continue
+		}
+		versionToClusterName[initialVersion] = name
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+	return merged, nil
+}
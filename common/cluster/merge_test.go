@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMergeClusterGroups(t *testing.T) {
+	a := map[string]config.ClusterInformation{
+		"a1": {Enabled: true, InitialFailoverVersion: 0, RPCAddress: "a1:7833"},
+		"a2": {Enabled: true, InitialFailoverVersion: 1, RPCAddress: "a2:7833"},
+	}
+	b := map[string]config.ClusterInformation{
+		"a2": {Enabled: true, InitialFailoverVersion: 1, RPCAddress: "a2:7833"},
+		"b1": {Enabled: false, InitialFailoverVersion: 2, RPCAddress: "b1:7833"},
+	}
+
+	merged, err := MergeClusterGroups(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]config.ClusterInformation{
+		"a1": a["a1"],
+		"a2": a["a2"],
+		"b1": b["b1"],
+	}, merged)
+
+	merged, err = MergeClusterGroups(a, nil)
+	require.NoError(t, err)
+	assert.Equal(t, a, merged)
+}
+
+func TestMergeClusterGroupsConflicts(t *testing.T) {
+	a := map[string]config.ClusterInformation{
+		"a1": {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "us"}},
+		"a2": {Enabled: true, InitialFailoverVersion: 1},
+	}
+
+	tests := []struct {
+		msg string
+		b   map[string]config.ClusterInformation
+		err string
+	}{
+		{
+			msg: "same name different config",
+			b: map[string]config.ClusterInformation{
+				"a1": {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "eu"}},
+			},
+			err: "cluster a1 is defined with different config",
+		},
+		{
+			msg: "same initial version different name",
+			b: map[string]config.ClusterInformation{
+				"b1": {Enabled: true, InitialFailoverVersion: 1},
+			},
+			err: "clusters a2 and b1 have the same initial failover version 1",
+		},
+		{
+			msg: "multiple conflicts",
+			b: map[string]config.ClusterInformation{
+				"a2": {Enabled: false, InitialFailoverVersion: 1},
+				"b1": {Enabled: true, InitialFailoverVersion: 0},
+			},
+			err: "cluster a2 is defined with different config; clusters a1 and b1 have the same initial failover version 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			merged, err := MergeClusterGroups(a, tt.b)
+			assert.Nil(t, merged)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
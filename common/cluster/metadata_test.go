@@ -0,0 +1,1192 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/cadence/common"
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/metrics"
+)
+
+func TestMetadataLazyClusterInfo(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+	)
+
+	assert.Equal(t, map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}, metadata.GetEnabledClusterInfo())
+	assert.Equal(t, map[string]config.ClusterInformation{
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}, metadata.GetRemoteClusterInfo())
+	assert.Equal(t, TestAllClusterInfo, metadata.GetAllClusterInfo())
+}
+
+func TestMetadataLazyClusterInfoConcurrentAccess(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+	)
+	// copies share the lazily computed cluster info
+	metadataCopy := metadata
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Len(t, metadata.GetEnabledClusterInfo(), 2)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Len(t, metadataCopy.GetRemoteClusterInfo(), 1)
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkNewMetadata(b *testing.B) {
+	clusterGroup := largeClusterGroup(1000, 10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewMetadata(10000, "cluster-0", "cluster-0", clusterGroup)
+	}
+}
+
+// largeClusterGroup builds a cluster group with numClusters clusters where only every enabledEvery cluster is enabled
+func largeClusterGroup(numClusters int, enabledEvery int) map[string]config.ClusterInformation {
+	clusterGroup := make(map[string]config.ClusterInformation, numClusters)
+	for i := 0; i < numClusters; i++ {
+		clusterGroup[fmt.Sprintf("cluster-%v", i)] = config.ClusterInformation{
+			Enabled:                i%enabledEvery == 0,
+			InitialFailoverVersion: int64(i),
+			RPCName:                "cadence-frontend",
+			RPCAddress:             fmt.Sprintf("127.0.0.1:%v", 7000+i),
+			RPCTransport:           "grpc",
+		}
+	}
+	return clusterGroup
+}
+
+func TestMetadataNearestRemoteCluster(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":     {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "us-east"}},
+		"east-b":    {Enabled: true, InitialFailoverVersion: 1, Tags: map[string]string{RegionTag: "us-east"}},
+		"east-a":    {Enabled: true, InitialFailoverVersion: 2, Tags: map[string]string{RegionTag: "us-east"}},
+		"west":      {Enabled: true, InitialFailoverVersion: 3, Tags: map[string]string{RegionTag: "us-west"}},
+		"eu":        {Enabled: true, InitialFailoverVersion: 4},
+		"west-down": {Enabled: false, InitialFailoverVersion: 5, Tags: map[string]string{RegionTag: "ap-south"}},
+	}
+	metadata := NewMetadata(10, "local", "local", clusterGroup)
+
+	tests := []struct {
+		msg             string
+		preferredRegion string
+		expected        string
+	}{
+		{msg: "single match", preferredRegion: "us-west", expected: "west"},
+		{msg: "multiple matches sorted by name", preferredRegion: "us-east", expected: "east-a"},
+		{msg: "no match falls back to any remote", preferredRegion: "eu-central", expected: "east-a"},
+		{msg: "disabled clusters are ignored", preferredRegion: "ap-south", expected: "east-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			clusterName, ok := metadata.NearestRemoteCluster(tt.preferredRegion)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, clusterName)
+		})
+	}
+
+	_, ok := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestSingleDCClusterInfo,
+	).NearestRemoteCluster("us-east")
+	assert.False(t, ok)
+}
+
+func TestMetadataIsVersionPlausible(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	maxGeneration := int64(5)
+
+	assert.True(t, metadata.IsVersionPlausible(TestAlternativeClusterInitialFailoverVersion, maxGeneration))
+	assert.True(t, metadata.IsVersionPlausible(maxGeneration*TestFailoverVersionIncrement, maxGeneration))
+	assert.True(t, metadata.IsVersionPlausible((maxGeneration+1)*TestFailoverVersionIncrement-1, maxGeneration))
+	assert.False(t, metadata.IsVersionPlausible((maxGeneration+1)*TestFailoverVersionIncrement, maxGeneration))
+	assert.False(t, metadata.IsVersionPlausible(1000*TestFailoverVersionIncrement, maxGeneration))
+	assert.False(t, metadata.IsVersionPlausible(-1, maxGeneration))
+	assert.True(t, metadata.IsVersionPlausible(common.EmptyVersion, maxGeneration))
+}
+
+func TestMetadataFailoverParticipants(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"c":        {Enabled: true, InitialFailoverVersion: 0},
+		"a":        {Enabled: true, InitialFailoverVersion: 1},
+		"disabled": {Enabled: false, InitialFailoverVersion: 2},
+		"archival": {Enabled: true, InitialFailoverVersion: 3, ArchivalOnly: true},
+		"b":        {Enabled: true, InitialFailoverVersion: 4},
+	}
+	metadata := NewMetadata(10, "c", "c", clusterGroup)
+
+	assert.Equal(t, []string{"a", "b", "c"}, metadata.FailoverParticipants())
+}
+
+func TestMetadataNextPrimaryCandidate(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"primary":  {Enabled: true, InitialFailoverVersion: 0},
+		"second":   {Enabled: true, InitialFailoverVersion: 1},
+		"disabled": {Enabled: false, InitialFailoverVersion: 2},
+		"third":    {Enabled: true, InitialFailoverVersion: 3},
+	}
+	metadata := NewMetadata(
+		10,
+		"primary",
+		"primary",
+		clusterGroup,
+		WithPrimaryFailoverOrder([]string{"primary", "disabled", "second", "third"}),
+	)
+
+	candidate, ok := metadata.NextPrimaryCandidate()
+	assert.True(t, ok)
+	assert.Equal(t, "primary", candidate)
+
+	candidate, ok = metadata.NextPrimaryCandidate("primary")
+	assert.True(t, ok)
+	assert.Equal(t, "second", candidate)
+
+	candidate, ok = metadata.NextPrimaryCandidate("primary", "second")
+	assert.True(t, ok)
+	assert.Equal(t, "third", candidate)
+
+	_, ok = metadata.NextPrimaryCandidate("primary", "second", "third")
+	assert.False(t, ok)
+
+	_, ok = GetTestClusterMetadata(true).NextPrimaryCandidate()
+	assert.False(t, ok)
+}
+
+func TestMetadataGetClusterInfoByInitialVersion(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	clusterName, info, ok := metadata.GetClusterInfoByInitialVersion(TestAlternativeClusterInitialFailoverVersion)
+	assert.True(t, ok)
+	assert.Equal(t, TestAlternativeClusterName, clusterName)
+	assert.Equal(t, TestAllClusterInfo[TestAlternativeClusterName], info)
+
+	clusterName, info, ok = metadata.GetClusterInfoByInitialVersion(TestDisabledClusterInitialFailoverVersion)
+	assert.True(t, ok)
+	assert.Equal(t, TestDisabledClusterName, clusterName)
+	assert.Equal(t, TestAllClusterInfo[TestDisabledClusterName], info)
+
+	_, _, ok = metadata.GetClusterInfoByInitialVersion(TestFailoverVersionIncrement - 1)
+	assert.False(t, ok)
+}
+
+func TestMetadataGetNextFailoverVersionSafe(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"aligned":    {Enabled: true, InitialFailoverVersion: 1},
+		"misaligned": {Enabled: true, InitialFailoverVersion: 12},
+	}
+	metadata := NewMetadata(10, "aligned", "aligned", clusterGroup)
+
+	failoverVersion, err := metadata.GetNextFailoverVersionSafe("aligned", 15)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), failoverVersion)
+
+	_, err = metadata.GetNextFailoverVersionSafe("misaligned", 15)
+	assert.EqualError(t, err, "cluster misaligned: initial failover version 12 is not within [0, 10)")
+	assert.Panics(t, func() { metadata.GetNextFailoverVersion("misaligned", 15) })
+
+	_, err = metadata.GetNextFailoverVersionSafe("unknown", 15)
+	assert.Error(t, err)
+	assert.Panics(t, func() { metadata.GetNextFailoverVersion("unknown", 15) })
+}
+
+func TestMetadataGetNextFailoverVersionBoundaries(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"first":  {Enabled: true, InitialFailoverVersion: 0},
+		"second": {Enabled: true, InitialFailoverVersion: 1},
+		"last":   {Enabled: true, InitialFailoverVersion: 99},
+	}
+	metadata := NewMetadata(100, "first", "first", clusterGroup)
+
+	tests := []struct {
+		cluster        string
+		currentVersion int64
+		expected       int64
+	}{
+		{cluster: "second", currentVersion: common.EmptyVersion, expected: 1},
+		{cluster: "second", currentVersion: 0, expected: 1},
+		{cluster: "second", currentVersion: 1, expected: 1},
+		{cluster: "second", currentVersion: 2, expected: 101},
+		{cluster: "second", currentVersion: 100, expected: 101},
+		{cluster: "second", currentVersion: 101, expected: 101},
+		{cluster: "first", currentVersion: common.EmptyVersion, expected: 0},
+		{cluster: "first", currentVersion: 0, expected: 0},
+		{cluster: "first", currentVersion: 1, expected: 100},
+		{cluster: "first", currentVersion: 100, expected: 100},
+		{cluster: "last", currentVersion: 99, expected: 99},
+		{cluster: "last", currentVersion: 100, expected: 199},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, metadata.GetNextFailoverVersion(tt.cluster, tt.currentVersion), "%v from %v", tt.cluster, tt.currentVersion)
+	}
+
+	// exhaustively verify the result is the smallest version >= input with the cluster's residue
+	for clusterName, info := range clusterGroup {
+		for currentVersion := int64(0); currentVersion < 500; currentVersion++ {
+			expected := currentVersion
+			for expected%100 != info.InitialFailoverVersion {
+				expected++
+			}
+			assert.Equal(t, expected, metadata.GetNextFailoverVersion(clusterName, currentVersion), "%v from %v", clusterName, currentVersion)
+		}
+	}
+}
+
+func TestMetadataActiveClustersForDomain(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	assert.Equal(t,
+		[]string{TestCurrentClusterName, TestAlternativeClusterName},
+		metadata.ActiveClustersForDomain([]string{TestAlternativeClusterName, TestCurrentClusterName}),
+	)
+	assert.Equal(t,
+		[]string{TestAlternativeClusterName},
+		metadata.ActiveClustersForDomain([]string{TestAlternativeClusterName, TestDisabledClusterName, "unknown"}),
+	)
+	assert.Empty(t, metadata.ActiveClustersForDomain(nil))
+}
+
+func TestMetadataIsVersionFromSameClusterSafe(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	same, ok := metadata.IsVersionFromSameClusterSafe(1, TestFailoverVersionIncrement+1)
+	assert.True(t, ok)
+	assert.True(t, same)
+
+	same, ok = metadata.IsVersionFromSameClusterSafe(1, TestFailoverVersionIncrement+2)
+	assert.True(t, ok)
+	assert.False(t, same)
+
+	// -24 and 6 are congruent modulo 10, but EmptyVersion belongs to no cluster
+	assert.True(t, metadata.IsVersionFromSameCluster(common.EmptyVersion, 6))
+	_, ok = metadata.IsVersionFromSameClusterSafe(common.EmptyVersion, 6)
+	assert.False(t, ok)
+	_, ok = metadata.IsVersionFromSameClusterSafe(6, common.EmptyVersion)
+	assert.False(t, ok)
+	_, ok = metadata.IsVersionFromSameClusterSafe(common.EmptyVersion, common.EmptyVersion)
+	assert.False(t, ok)
+}
+
+func BenchmarkClusterNameForFailoverVersion(b *testing.B) {
+	clusterGroup := largeClusterGroup(1000, 10)
+	versions := make([]int64, 100)
+	for i := range versions {
+		versions[i] = int64(i*10000 + i*10)
+	}
+
+	metadata := NewMetadata(10000, "cluster-0", "cluster-0", clusterGroup)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metadata.ClusterNameForFailoverVersion(versions[i%len(versions)])
+	}
+}
+
+func TestMetadataIsReplicationCompatible(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	compatible, reasons := metadata.IsReplicationCompatible(TestPassiveClusterMetadata)
+	assert.True(t, compatible)
+	assert.Empty(t, reasons)
+
+	// clusters which are not shared are ignored
+	compatible, reasons = metadata.IsReplicationCompatible(NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestSingleDCClusterInfo,
+	))
+	assert.True(t, compatible)
+	assert.Empty(t, reasons)
+
+	compatible, reasons = metadata.IsReplicationCompatible(NewMetadata(
+		TestFailoverVersionIncrement*10,
+		TestCurrentClusterName,
+		TestAlternativeClusterName,
+		TestAllClusterInfo,
+	))
+	assert.False(t, compatible)
+	assert.Equal(t, []string{"failover version increment mismatch: 10 vs 100"}, reasons)
+
+	alternativeInfo := TestAllClusterInfo[TestAlternativeClusterName]
+	alternativeInfo.InitialFailoverVersion = 5
+	compatible, reasons = metadata.IsReplicationCompatible(NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestAlternativeClusterName,
+		map[string]config.ClusterInformation{
+			TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+			TestAlternativeClusterName: alternativeInfo,
+		},
+	))
+	assert.False(t, compatible)
+	assert.Equal(t, []string{"cluster standby: initial failover version mismatch: 1 vs 5"}, reasons)
+}
+
+func TestMetadataResolveFailoverVersion(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	tests := []struct {
+		version            int64
+		expectedCluster    string
+		expectedGeneration int64
+	}{
+		{version: 0, expectedCluster: TestCurrentClusterName, expectedGeneration: 0},
+		{version: 1, expectedCluster: TestAlternativeClusterName, expectedGeneration: 0},
+		{version: 20, expectedCluster: TestCurrentClusterName, expectedGeneration: 2},
+		{version: 2, expectedCluster: TestDisabledClusterName, expectedGeneration: 0},
+		{version: 351, expectedCluster: TestAlternativeClusterName, expectedGeneration: 35},
+		{version: common.EmptyVersion, expectedCluster: TestCurrentClusterName, expectedGeneration: 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("version %v", tt.version), func(t *testing.T) {
+			cluster, generation, err := metadata.ResolveFailoverVersion(tt.version)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCluster, cluster)
+			assert.Equal(t, tt.expectedGeneration, generation)
+		})
+	}
+
+	_, _, err := metadata.ResolveFailoverVersion(15)
+	assert.Error(t, err)
+	assert.Panics(t, func() { metadata.ClusterNameForFailoverVersion(15) })
+}
+
+func TestMetadataIsClusterCompatibleWith(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"upgraded": {Enabled: true, InitialFailoverVersion: 0, Capabilities: []string{"a", "b"}},
+		"legacy":   {Enabled: true, InitialFailoverVersion: 1, Capabilities: []string{"a"}},
+	}
+	metadata := NewMetadata(10, "upgraded", "upgraded", clusterGroup)
+
+	compatible, err := metadata.IsClusterCompatibleWith("upgraded", "b")
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = metadata.IsClusterCompatibleWith("legacy", "a")
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = metadata.IsClusterCompatibleWith("legacy", "b")
+	assert.NoError(t, err)
+	assert.False(t, compatible)
+
+	_, err = metadata.IsClusterCompatibleWith("unknown", "a")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+}
+
+func TestMetadataManualFailoverVersion(t *testing.T) {
+	tests := []struct {
+		offset         int64
+		targetCluster  string
+		currentVersion int64
+		expected       int64
+	}{
+		{offset: 0, targetCluster: TestAlternativeClusterName, currentVersion: 0, expected: 11},
+		{offset: 0, targetCluster: TestAlternativeClusterName, currentVersion: 1, expected: 11},
+		{offset: 0, targetCluster: TestCurrentClusterName, currentVersion: 1, expected: 10},
+		{offset: 1, targetCluster: TestAlternativeClusterName, currentVersion: 0, expected: 11},
+		{offset: 1, targetCluster: TestAlternativeClusterName, currentVersion: 21, expected: 51},
+		{offset: 3, targetCluster: TestCurrentClusterName, currentVersion: 21, expected: 70},
+		{offset: 1, targetCluster: TestCurrentClusterName, currentVersion: common.EmptyVersion, expected: 10},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v from %v with offset %v", tt.targetCluster, tt.currentVersion, tt.offset), func(t *testing.T) {
+			metadata := NewMetadata(
+				TestFailoverVersionIncrement,
+				TestCurrentClusterName,
+				TestCurrentClusterName,
+				TestAllClusterInfo,
+				WithManualFailoverGenerationOffset(tt.offset),
+			)
+			failoverVersion, err := metadata.ManualFailoverVersion(tt.targetCluster, tt.currentVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, failoverVersion)
+			assert.Greater(t, failoverVersion, tt.currentVersion)
+			assert.Equal(t, tt.targetCluster, metadata.ClusterNameForFailoverVersion(failoverVersion))
+			assert.Equal(t, FailoverTypeManual, metadata.FailoverTypeOfVersion(failoverVersion))
+		})
+	}
+
+	_, err := GetTestClusterMetadata(true).ManualFailoverVersion("unknown", 0)
+	assert.Error(t, err)
+}
+
+func TestMetadataManualFailoverVersionDefaultOffset(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	// DefaultManualFailoverGenerationOffset skips at least one generation beyond NextFailoverVersionAfter
+	nextVersion, err := metadata.NextFailoverVersionAfter(TestAlternativeClusterName, 21)
+	assert.NoError(t, err)
+	failoverVersion, err := metadata.ManualFailoverVersion(TestAlternativeClusterName, 21)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(31), nextVersion)
+	// 41 is at an even generation, which encodes automatic failovers
+	assert.Equal(t, int64(51), failoverVersion)
+	assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(failoverVersion))
+}
+
+func TestMetadataUnknownVersionPolicyCurrentCluster(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithMetricsClient(metrics.NewClient(scope, metrics.History)),
+		WithUnknownVersionPolicy(UnknownVersionPolicyCurrentCluster),
+	)
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, TestCurrentClusterName, metadata.ClusterNameForFailoverVersion(15))
+		assert.Equal(t, TestCurrentClusterName, metadata.ClusterNameForFailoverVersion(25))
+		assert.Equal(t, TestCurrentClusterName, metadata.ClusterNameForFailoverVersion(27))
+		assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(21))
+	})
+
+	counters := map[string]int64{}
+	for _, counter := range scope.Snapshot().Counters() {
+		assert.Equal(t, "cluster_metadata_unknown_failover_version", counter.Name())
+		counters[counter.Tags()["failover_version_residue"]] += counter.Value()
+	}
+	assert.Equal(t, map[string]int64{"5": 2, "7": 1}, counters)
+
+	clusterName, err := metadata.ClusterNameForFailoverVersionSafe(15)
+	assert.NoError(t, err)
+	assert.Equal(t, TestCurrentClusterName, clusterName)
+}
+
+func TestMetadataUnknownFailoverVersionCountedBeforePanic(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithMetricsClient(metrics.NewClient(scope, metrics.History)),
+	)
+
+	assert.Panics(t, func() { metadata.ClusterNameForFailoverVersion(15) })
+	assert.Len(t, scope.Snapshot().Counters(), 1)
+}
+
+func TestMetadataUnknownVersionPolicyError(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithUnknownVersionPolicy(UnknownVersionPolicyError),
+	)
+
+	_, err := metadata.ClusterNameForFailoverVersionSafe(15)
+	assert.EqualError(t, err, "unknown initial failover version 5 with given cluster initial failover version map: "+
+		fmt.Sprint(TestAllClusterInfo)+" and failover version increment 10")
+	clusterName, err := metadata.ClusterNameForFailoverVersionSafe(21)
+	assert.NoError(t, err)
+	assert.Equal(t, TestAlternativeClusterName, clusterName)
+	// an unknown failover version never resolves to an empty cluster name
+	assert.Panics(t, func() { metadata.ClusterNameForFailoverVersion(15) })
+	assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(21))
+}
+
+func TestMetadataUnknownVersionPolicyPanic(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithUnknownVersionPolicy(UnknownVersionPolicyPanic),
+	)
+
+	assert.Panics(t, func() { metadata.ClusterNameForFailoverVersion(15) })
+	assert.Panics(t, func() { _, _ = metadata.ClusterNameForFailoverVersionSafe(15) })
+	assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(21))
+}
+
+func TestMetadataGetCurrentClusterInformation(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	info, ok := metadata.GetCurrentClusterInformation()
+	assert.True(t, ok)
+	assert.Equal(t, TestAllClusterInfo[TestCurrentClusterName], info)
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, "missing", TestAllClusterInfo)
+	_, ok = metadata.GetCurrentClusterInformation()
+	assert.False(t, ok)
+}
+
+func TestMetadataIsCurrentClusterPrimary(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.True(t, metadata.IsCurrentClusterPrimary())
+	assert.False(t, metadata.HasRemotePrimary())
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.False(t, metadata.IsCurrentClusterPrimary())
+	assert.True(t, metadata.HasRemotePrimary())
+}
+
+func TestMetadataNextFailoverVersions(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		TestDisabledClusterName:    TestAllClusterInfo[TestDisabledClusterName],
+		"invalid":                  {Enabled: true, InitialFailoverVersion: TestFailoverVersionIncrement},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+
+	for _, currentVersion := range []int64{0, 1, 15, 20, 101} {
+		failoverVersions := metadata.NextFailoverVersions(currentVersion)
+		assert.Len(t, failoverVersions, 2)
+		for _, clusterName := range []string{TestCurrentClusterName, TestAlternativeClusterName} {
+			assert.Equal(t, metadata.GetNextFailoverVersion(clusterName, currentVersion), failoverVersions[clusterName])
+		}
+	}
+}
+
+func TestMetadataMetricsSnapshot(t *testing.T) {
+	assert.Equal(t, map[string]float64{
+		"cluster_count":              3,
+		"enabled_cluster_count":      2,
+		"remote_cluster_count":       1,
+		"failover_version_increment": 10,
+		"is_primary":                 1,
+	}, GetTestClusterMetadata(true).MetricsSnapshot())
+
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.Equal(t, float64(0), metadata.MetricsSnapshot()["is_primary"])
+}
+
+func TestMetadataNextFailoverVersionForCurrentCluster(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	for _, currentVersion := range []int64{common.EmptyVersion, 0, 1, 10, 15, 101} {
+		assert.Equal(
+			t,
+			metadata.GetNextFailoverVersion(TestCurrentClusterName, currentVersion),
+			metadata.NextFailoverVersionForCurrentCluster(currentVersion),
+		)
+	}
+}
+
+func TestMetadataForEachFailoverVersionMapping(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement*10, "cluster-0", "cluster-0", largeClusterGroup(50, 1))
+
+	var initialVersions []int64
+	metadata.ForEachFailoverVersionMapping(func(initialVersion int64, clusterName string) {
+		assert.Equal(t, fmt.Sprintf("cluster-%v", initialVersion), clusterName)
+		initialVersions = append(initialVersions, initialVersion)
+	})
+	assert.Len(t, initialVersions, 50)
+	assert.True(t, sort.SliceIsSorted(initialVersions, func(i, j int) bool { return initialVersions[i] < initialVersions[j] }))
+}
+
+func TestMetadataIsFailoverAllowed(t *testing.T) {
+	assert.True(t, GetTestClusterMetadata(true).IsFailoverAllowed())
+
+	failoverEnabled, failoverDisabled := true, false
+	clusterGroup := map[string]config.ClusterInformation{
+		"enabled":  {Enabled: true, InitialFailoverVersion: 0, FailoverEnabled: &failoverEnabled},
+		"disabled": {Enabled: true, InitialFailoverVersion: 1, FailoverEnabled: &failoverDisabled},
+	}
+	assert.True(t, NewMetadata(TestFailoverVersionIncrement, "enabled", "enabled", clusterGroup).IsFailoverAllowed())
+	assert.False(t, NewMetadata(TestFailoverVersionIncrement, "enabled", "disabled", clusterGroup).IsFailoverAllowed())
+}
+
+func TestMetadataBucketVersionsByCluster(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	buckets, unknownVersions := metadata.BucketVersionsByCluster([]int64{0, 11, 25, 20, common.EmptyVersion, 1, 12, 7})
+	assert.Equal(t, map[string][]int64{
+		TestCurrentClusterName:     {0, 20, common.EmptyVersion},
+		TestAlternativeClusterName: {11, 1},
+		TestDisabledClusterName:    {12},
+	}, buckets)
+	assert.Equal(t, []int64{25, 7}, unknownVersions)
+
+	buckets, unknownVersions = metadata.BucketVersionsByCluster(nil)
+	assert.Empty(t, buckets)
+	assert.Empty(t, unknownVersions)
+}
+
+func TestMetadataBaseFailoverVersion(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	for clusterName, info := range TestAllClusterInfo {
+		version, err := metadata.BaseFailoverVersion(clusterName)
+		assert.NoError(t, err)
+		assert.Equal(t, info.InitialFailoverVersion, version)
+
+		resolved, generation, err := metadata.ResolveFailoverVersion(version)
+		assert.NoError(t, err)
+		assert.Equal(t, clusterName, resolved)
+		assert.Equal(t, int64(0), generation)
+	}
+
+	_, err := metadata.BaseFailoverVersion("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+}
+
+func TestMetadataShouldRedirectDomainMutations(t *testing.T) {
+	redirect, redirectCluster := GetTestClusterMetadata(true).ShouldRedirectDomainMutations()
+	assert.False(t, redirect)
+	assert.Empty(t, redirectCluster)
+
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	redirect, redirectCluster = metadata.ShouldRedirectDomainMutations()
+	assert.True(t, redirect)
+	assert.Equal(t, TestAlternativeClusterName, redirectCluster)
+	assert.Equal(t, TestAllClusterInfo[TestAlternativeClusterName].RPCAddress, metadata.GetAllClusterInfo()[redirectCluster].RPCAddress)
+}
+
+func TestMetadataQuarantineVersions(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	versions, err := metadata.QuarantineVersions(TestAlternativeClusterName, 2, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{21, 31, 41, 51}, versions)
+	for _, version := range versions {
+		assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(version))
+	}
+
+	versions, err = metadata.QuarantineVersions(TestCurrentClusterName, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{TestCurrentClusterInitialFailoverVersion}, versions)
+
+	_, err = metadata.QuarantineVersions("unknown", 0, 1)
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+	_, err = metadata.QuarantineVersions(TestCurrentClusterName, 3, 2)
+	assert.EqualError(t, err, "invalid generation range [3, 2]")
+	_, err = metadata.QuarantineVersions(TestCurrentClusterName, -1, 2)
+	assert.EqualError(t, err, "invalid generation range [-1, 2]")
+
+	// huge ranges
+	_, err = metadata.QuarantineVersions(TestAlternativeClusterName, 0, math.MaxInt64)
+	assert.EqualError(t, err, "generation range [0, 9223372036854775807] exceeds 65536 generations")
+	_, err = metadata.QuarantineVersions(TestAlternativeClusterName, 0, MaxQuarantineGenerations)
+	assert.EqualError(t, err, "generation range [0, 65536] exceeds 65536 generations")
+	versions, err = metadata.QuarantineVersions(TestAlternativeClusterName, 1, MaxQuarantineGenerations)
+	assert.NoError(t, err)
+	assert.Len(t, versions, int(MaxQuarantineGenerations))
+
+	// the last generation of the alternative cluster is (math.MaxInt64 - 1) / 10
+	maxGeneration := int64(math.MaxInt64-1) / TestFailoverVersionIncrement
+	versions, err = metadata.QuarantineVersions(TestAlternativeClusterName, maxGeneration-1, maxGeneration)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{math.MaxInt64 - 16, math.MaxInt64 - 6}, versions)
+	_, err = metadata.QuarantineVersions(TestAlternativeClusterName, maxGeneration, math.MaxInt64)
+	assert.Error(t, err)
+	_, err = metadata.QuarantineVersions(TestAlternativeClusterName, maxGeneration, maxGeneration+1)
+	assert.EqualError(t, err, "cluster standby: failover versions overflow beyond generation 922337203685477580")
+}
+
+func TestMetadataEqual(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	clusterGroup := map[string]config.ClusterInformation{}
+	for clusterName, info := range TestAllClusterInfo {
+		clusterGroup[clusterName] = info
+	}
+	assert.True(t, metadata.Equal(NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)))
+	assert.True(t, metadata.Equal(metadata))
+
+	updatedInfo := TestAllClusterInfo[TestAlternativeClusterName]
+	updatedInfo.Tags = map[string]string{RegionTag: "us-west"}
+	updatedClusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: updatedInfo,
+		TestDisabledClusterName:    TestAllClusterInfo[TestDisabledClusterName],
+	}
+	smallerClusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}
+	renamedClusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		"renamed":                  TestAllClusterInfo[TestDisabledClusterName],
+	}
+	tests := map[string]Metadata{
+		"increment":       NewMetadata(TestFailoverVersionIncrement*10, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo),
+		"primary":         NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestCurrentClusterName, TestAllClusterInfo),
+		"current":         NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestAlternativeClusterName, TestAllClusterInfo),
+		"cluster info":    NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, updatedClusterGroup),
+		"fewer clusters":  NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, smallerClusterGroup),
+		"cluster renamed": NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, renamedClusterGroup),
+	}
+	for name, other := range tests {
+		assert.False(t, metadata.Equal(other), name)
+		assert.False(t, other.Equal(metadata), name)
+	}
+}
+
+func TestMetadataAllClustersOrdered(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"c": {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "us-east"}, Capabilities: []string{"x"}},
+		"a": {Enabled: true, InitialFailoverVersion: 1},
+		"b": {Enabled: false, InitialFailoverVersion: 2, ReplicaClusters: []string{"a"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "c", "c", clusterGroup)
+
+	clusters := metadata.AllClustersOrdered()
+	assert.Len(t, clusters, 3)
+	for i, clusterName := range []string{"a", "b", "c"} {
+		assert.Equal(t, clusterName, clusters[i].Name)
+		assert.Equal(t, clusterGroup[clusterName], clusters[i].Info)
+	}
+
+	clusters[2].Info.Enabled = false
+	clusters[2].Info.Tags[RegionTag] = "us-west"
+	clusters[2].Info.Capabilities[0] = "y"
+	clusters[1].Info.ReplicaClusters[0] = "c"
+	assert.Equal(t, clusterGroup, metadata.GetAllClusterInfo())
+	assert.Equal(t, "us-east", metadata.GetAllClusterInfo()["c"].Tags[RegionTag])
+	assert.Equal(t, []string{"x"}, metadata.GetAllClusterInfo()["c"].Capabilities)
+	assert.Equal(t, []string{"a"}, metadata.GetAllClusterInfo()["b"].ReplicaClusters)
+}
+
+func TestMetadataToClusterGroup(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{}
+	for clusterName, info := range TestAllClusterInfo {
+		clusterGroup[clusterName] = copyClusterInformation(info)
+	}
+	standby := clusterGroup[TestAlternativeClusterName]
+	standby.Tags = map[string]string{RegionTag: "us-west"}
+	standby.ReplicaClusters = []string{TestCurrentClusterName}
+	clusterGroup[TestAlternativeClusterName] = standby
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+
+	require.NoError(t, metadata.SetClusterEnabled(TestAlternativeClusterName, false))
+	require.NoError(t, metadata.UpdateClusterRPCAddress(TestCurrentClusterName, "127.0.0.1:9933"))
+
+	exported := metadata.ToClusterGroup()
+	assert.Equal(t, metadata.GetAllClusterInfo(), exported)
+	assert.False(t, exported[TestAlternativeClusterName].Enabled)
+	assert.Equal(t, "127.0.0.1:9933", exported[TestCurrentClusterName].RPCAddress)
+
+	reconstructed := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, exported)
+	assert.Equal(t, metadata.GetAllClusterInfo(), reconstructed.GetAllClusterInfo())
+	assert.Equal(t, metadata.GetEnabledClusterInfo(), reconstructed.GetEnabledClusterInfo())
+	assert.Equal(t, metadata.TopologyHash(), reconstructed.TopologyHash())
+
+	// the export is a deep copy
+	exported = metadata.ToClusterGroup()
+	exported[TestAlternativeClusterName].Tags[RegionTag] = "us-east"
+	exported[TestAlternativeClusterName].ReplicaClusters[0] = "other"
+	delete(exported, TestCurrentClusterName)
+	assert.Equal(t, "us-west", metadata.GetAllClusterInfo()[TestAlternativeClusterName].Tags[RegionTag])
+	assert.Equal(t, []string{TestCurrentClusterName}, metadata.GetAllClusterInfo()[TestAlternativeClusterName].ReplicaClusters)
+	assert.Len(t, metadata.GetAllClusterInfo(), len(TestAllClusterInfo))
+}
+
+func TestMetadataNextFailoverVersionAfter(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	tests := []struct {
+		cluster      string
+		afterVersion int64
+		expected     int64
+	}{
+		{cluster: TestCurrentClusterName, afterVersion: common.EmptyVersion, expected: 0},
+		{cluster: TestAlternativeClusterName, afterVersion: common.EmptyVersion, expected: 1},
+		{cluster: TestAlternativeClusterName, afterVersion: 0, expected: 1},
+		{cluster: TestAlternativeClusterName, afterVersion: 1, expected: 11},
+		{cluster: TestAlternativeClusterName, afterVersion: 12, expected: 21},
+		{cluster: TestCurrentClusterName, afterVersion: 11, expected: 20},
+		{cluster: TestCurrentClusterName, afterVersion: 20, expected: 30},
+		{cluster: TestDisabledClusterName, afterVersion: 21, expected: 22},
+	}
+	for _, tt := range tests {
+		version, err := metadata.NextFailoverVersionAfter(tt.cluster, tt.afterVersion)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, version, "%v after %v", tt.cluster, tt.afterVersion)
+		assert.Equal(t, tt.cluster, metadata.ClusterNameForFailoverVersion(version))
+	}
+
+	// GetNextFailoverVersion returns the version itself if it already belongs to the cluster
+	assert.Equal(t, int64(11), metadata.GetNextFailoverVersion(TestAlternativeClusterName, 11))
+	version, err := metadata.NextFailoverVersionAfter(TestAlternativeClusterName, 11)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), version)
+
+	_, err = metadata.NextFailoverVersionAfter("unknown", 11)
+	assert.Error(t, err)
+}
+
+func TestMetadataCurrentClusterGeneration(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+
+	generation, err := metadata.CurrentClusterGeneration(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), generation)
+	generation, err = metadata.CurrentClusterGeneration(120)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), generation)
+
+	_, err = metadata.CurrentClusterGeneration(121)
+	assert.EqualError(t, err, "failover version 121 does not belong to the current cluster active")
+	_, err = metadata.CurrentClusterGeneration(common.EmptyVersion)
+	assert.Error(t, err)
+	_, err = metadata.CurrentClusterGeneration(125)
+	assert.Error(t, err)
+}
+
+func TestMetadataGetClusterRPCTransport(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"grpc":     {Enabled: true, InitialFailoverVersion: 0, RPCTransport: "grpc"},
+		"tchannel": {Enabled: true, InitialFailoverVersion: 1, RPCTransport: "tchannel"},
+		"default":  {Enabled: true, InitialFailoverVersion: 2},
+		"invalid":  {Enabled: true, InitialFailoverVersion: 3, RPCTransport: "http"},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "grpc", "grpc", clusterGroup)
+
+	for clusterName, expected := range map[string]string{"grpc": "grpc", "tchannel": "tchannel", "default": "tchannel"} {
+		transport, err := metadata.GetClusterRPCTransport(clusterName)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, transport, clusterName)
+	}
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, "grpc", "grpc", clusterGroup, WithDefaultRPCTransport("grpc"))
+	transport, err := metadata.GetClusterRPCTransport("default")
+	assert.NoError(t, err)
+	assert.Equal(t, "grpc", transport)
+
+	_, err = metadata.GetClusterRPCTransport("invalid")
+	assert.EqualError(t, err, "cluster invalid: unsupported rpc transport http")
+	_, err = metadata.GetClusterRPCTransport("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, "grpc", "grpc", clusterGroup, WithDefaultRPCTransport("http"))
+	_, err = metadata.GetClusterRPCTransport("default")
+	assert.EqualError(t, err, "cluster default: unsupported rpc transport http")
+
+	// validated config without rpc transport uses the default
+	defaultInfo := TestAllClusterInfo[TestCurrentClusterName]
+	defaultInfo.RPCTransport = ""
+	metadata, err = NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup:             map[string]config.ClusterInformation{TestCurrentClusterName: defaultInfo},
+	}, WithDefaultRPCTransport("grpc"))
+	require.NoError(t, err)
+	transport, err = metadata.GetClusterRPCTransport(TestCurrentClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, "grpc", transport)
+}
+
+func TestMetadataIsCurrentCluster(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.True(t, metadata.IsCurrentCluster(TestCurrentClusterName))
+	assert.False(t, metadata.IsCurrentCluster(TestAlternativeClusterName))
+	assert.False(t, metadata.IsCurrentCluster(strings.ToUpper(TestCurrentClusterName)))
+
+	metadata = NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithClusterNameNormalizer(strings.ToLower),
+	)
+	assert.True(t, metadata.IsCurrentCluster(strings.ToUpper(TestCurrentClusterName)))
+	assert.False(t, metadata.IsCurrentCluster(strings.ToUpper(TestAlternativeClusterName)))
+}
+
+func TestMetadataOnResolveDisabledCluster(t *testing.T) {
+	var resolved []int64
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithOnResolveDisabledCluster(func(clusterName string, failoverVersion int64) {
+			assert.Equal(t, TestDisabledClusterName, clusterName)
+			resolved = append(resolved, failoverVersion)
+		}),
+	)
+
+	disabledVersion := TestDisabledClusterInitialFailoverVersion + TestFailoverVersionIncrement
+	assert.Equal(t, TestCurrentClusterName, metadata.ClusterNameForFailoverVersion(TestCurrentClusterInitialFailoverVersion))
+	assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(TestAlternativeClusterInitialFailoverVersion))
+	assert.Equal(t, TestDisabledClusterName, metadata.ClusterNameForFailoverVersion(disabledVersion))
+	assert.Equal(t, []int64{disabledVersion}, resolved)
+}
+
+func TestMetadataChildFailoverVersion(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	// same generation as the parent
+	version, err := metadata.ChildFailoverVersion(20, TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), version)
+	version, err = metadata.ChildFailoverVersion(20, TestCurrentClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), version)
+
+	// the child cluster residue is lower, wraps to the next generation
+	version, err = metadata.ChildFailoverVersion(22, TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(31), version)
+
+	_, err = metadata.ChildFailoverVersion(20, "unknown")
+	assert.EqualError(t, err, "unknown child cluster name: unknown")
+}
+
+func TestMetadataIsChildVersionValid(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	// later generation, from another cluster
+	assert.True(t, metadata.IsChildVersionValid(21, 30))
+	// same generation, lower residue of another cluster
+	assert.True(t, metadata.IsChildVersionValid(21, 20))
+	assert.True(t, metadata.IsChildVersionValid(21, 21))
+	// stale child
+	assert.False(t, metadata.IsChildVersionValid(21, 11))
+	assert.False(t, metadata.IsChildVersionValid(30, 21))
+
+	assert.True(t, metadata.IsChildVersionValid(common.EmptyVersion, 11))
+	assert.True(t, metadata.IsChildVersionValid(common.EmptyVersion, common.EmptyVersion))
+	assert.False(t, metadata.IsChildVersionValid(1, common.EmptyVersion))
+	assert.False(t, metadata.IsChildVersionValid(0, common.EmptyVersion))
+}
+
+func TestMetadataGetClusterMetricTags(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName: TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: func() config.ClusterInformation {
+			info := TestAllClusterInfo[TestAlternativeClusterName]
+			info.Tags = map[string]string{RegionTag: "us-east", "environment": "prod", ClusterNameMetricTag: "other"}
+			return info
+		}(),
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+
+	tags, err := metadata.GetClusterMetricTags(TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		RegionTag:            "us-east",
+		"environment":        "prod",
+		ClusterNameMetricTag: TestAlternativeClusterName,
+	}, tags)
+	// the configured tags are not modified
+	assert.Equal(t, "other", metadata.GetAllClusterInfo()[TestAlternativeClusterName].Tags[ClusterNameMetricTag])
+
+	tags, err = metadata.GetClusterMetricTags(TestCurrentClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{ClusterNameMetricTag: TestCurrentClusterName}, tags)
+
+	_, err = metadata.GetClusterMetricTags("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+}
+
+func TestMetadataMaxFailoverGeneration(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithMaxFailoverGeneration(5),
+	)
+
+	version, err := metadata.GetNextFailoverVersionSafe(TestAlternativeClusterName, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(51), version)
+
+	// the next version of the alternative cluster after 52 is at generation 6
+	_, err = metadata.GetNextFailoverVersionSafe(TestAlternativeClusterName, 52)
+	assert.True(t, errors.Is(err, ErrFailoverGenerationExceeded))
+	assert.EqualError(t, err, "cluster standby: next failover version 61 after 52: failover generation exceeds the max failover generation")
+	// the max failover generation only applies to the error returning variant
+	assert.Equal(t, int64(61), metadata.GetNextFailoverVersion(TestAlternativeClusterName, 52))
+
+	// the generation offset of manual failovers counts towards the max failover generation
+	version, err = metadata.ManualFailoverVersion(TestAlternativeClusterName, 31)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(51), version)
+	_, err = metadata.ManualFailoverVersion(TestAlternativeClusterName, 41)
+	assert.True(t, errors.Is(err, ErrFailoverGenerationExceeded))
+	assert.EqualError(t, err, "cluster standby: next failover version 61 after 61: failover generation exceeds the max failover generation")
+
+	// no limit by default
+	version, err = GetTestClusterMetadata(true).GetNextFailoverVersionSafe(TestAlternativeClusterName, 52)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(61), version)
+}
+
+func TestMetadataUnionReplicationTargets(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	assert.Equal(t, []string{TestCurrentClusterName, TestAlternativeClusterName}, metadata.UnionReplicationTargets([][]string{
+		{TestAlternativeClusterName, TestDisabledClusterName},
+		{TestCurrentClusterName, TestAlternativeClusterName},
+		{"unknown"},
+		nil,
+	}))
+	assert.Empty(t, metadata.UnionReplicationTargets([][]string{{TestDisabledClusterName}}))
+	assert.Empty(t, metadata.UnionReplicationTargets(nil))
+}
+
+func TestMetadataFailoverVersionsAtGeneration(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	failoverVersions := metadata.FailoverVersionsAtGeneration(7)
+	assert.Equal(t, map[string]int64{
+		TestCurrentClusterName:     70,
+		TestAlternativeClusterName: 71,
+		TestDisabledClusterName:    72,
+	}, failoverVersions)
+	for clusterName, failoverVersion := range failoverVersions {
+		cluster, generation, err := metadata.ResolveFailoverVersion(failoverVersion)
+		assert.NoError(t, err)
+		assert.Equal(t, clusterName, cluster)
+		assert.Equal(t, int64(7), generation)
+	}
+
+	assert.Equal(t, map[string]int64{
+		TestCurrentClusterName:     TestCurrentClusterInitialFailoverVersion,
+		TestAlternativeClusterName: TestAlternativeClusterInitialFailoverVersion,
+		TestDisabledClusterName:    TestDisabledClusterInitialFailoverVersion,
+	}, metadata.FailoverVersionsAtGeneration(0))
+	assert.Nil(t, metadata.FailoverVersionsAtGeneration(-1))
+}
+
+func TestMetadataResidueZeroFailoverVersion(t *testing.T) {
+	metadata := NewMetadata(100, TestCurrentClusterName, TestCurrentClusterName, map[string]config.ClusterInformation{
+		TestCurrentClusterName: {Enabled: true, InitialFailoverVersion: 0},
+		"other":                {Enabled: true, InitialFailoverVersion: 5},
+	})
+	for _, version := range []int64{0, 100, 1000} {
+		assert.Equal(t, TestCurrentClusterName, metadata.ClusterNameForFailoverVersion(version))
+	}
+
+	metadata = NewMetadata(100, "other", "other", map[string]config.ClusterInformation{
+		"other": {Enabled: true, InitialFailoverVersion: 5},
+	})
+	_, _, err := metadata.ResolveFailoverVersion(100)
+	assert.EqualError(t, err, "failover version 100 is a multiple of the failover version increment 100, but no cluster has initial failover version 0")
+	assert.PanicsWithValue(t,
+		"failover version 100 is a multiple of the failover version increment 100, but no cluster has initial failover version 0",
+		func() { metadata.ClusterNameForFailoverVersion(100) },
+	)
+	assert.Equal(t, "other", metadata.ClusterNameForFailoverVersion(105))
+
+	metadata = NewMetadata(100, "other", "other", metadata.GetAllClusterInfo(), WithUnknownVersionPolicy(UnknownVersionPolicyError))
+	_, err = metadata.ClusterNameForFailoverVersionSafe(100)
+	assert.EqualError(t, err, "failover version 100 is a multiple of the failover version increment 100, but no cluster has initial failover version 0")
+}
+
+func TestMetadataLocalVersionForRemoteVersion(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestAlternativeClusterName, TestAllClusterInfo)
+
+	tests := map[int64]int64{
+		20: 21, // remote version of the current cluster
+		32: 31, // remote residue higher than the current cluster residue
+		21: 21, // local version
+		1:  1,
+	}
+	for remoteVersion, localVersion := range tests {
+		version, err := metadata.LocalVersionForRemoteVersion(remoteVersion)
+		assert.NoError(t, err)
+		assert.Equal(t, localVersion, version, "remote version: %v", remoteVersion)
+		assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(version))
+	}
+
+	version, err := metadata.LocalVersionForRemoteVersion(common.EmptyVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, common.EmptyVersion, version)
+
+	_, err = metadata.LocalVersionForRemoteVersion(25)
+	assert.Error(t, err)
+}
+
+func TestMetadataGetNextFailoverVersionSkipping(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithMaxFailoverGeneration(10),
+	)
+
+	// the natural next generation is not skipped
+	version, err := metadata.GetNextFailoverVersionSkipping(TestAlternativeClusterName, 20, map[int64]bool{3: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), version)
+
+	// the natural next generation and the one after it are skipped
+	version, err = metadata.GetNextFailoverVersionSkipping(TestAlternativeClusterName, 20, map[int64]bool{2: true, 3: true, 5: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(41), version)
+
+	version, err = metadata.GetNextFailoverVersionSkipping(TestAlternativeClusterName, 20, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), version)
+
+	_, err = metadata.GetNextFailoverVersionSkipping(TestAlternativeClusterName, 90, map[int64]bool{9: true, 10: true})
+	assert.True(t, errors.Is(err, ErrFailoverGenerationExceeded))
+	_, err = metadata.GetNextFailoverVersionSkipping("unknown", 20, nil)
+	assert.Error(t, err)
+}
+
+func TestMetadataHasEnabledRemoteInRegion(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":    {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "us-east"}},
+		"west":     {Enabled: true, InitialFailoverVersion: 1, Tags: map[string]string{RegionTag: "us-west"}},
+		"disabled": {Enabled: false, InitialFailoverVersion: 2, Tags: map[string]string{RegionTag: "eu-central"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "local", "local", clusterGroup)
+
+	assert.True(t, metadata.HasEnabledRemoteInRegion("us-west"))
+	// the current cluster is not a remote cluster
+	assert.False(t, metadata.HasEnabledRemoteInRegion("us-east"))
+	assert.False(t, metadata.HasEnabledRemoteInRegion("eu-central"))
+	assert.False(t, metadata.HasEnabledRemoteInRegion("ap-south"))
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/uber/cadence/common/clock"
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/metrics"
+)
+
+type (
+	// Option is used to provide optional configuration of Metadata
+	Option func(m *Metadata)
+
+	// ClusterInformationTransform transforms the info of a cluster on load, e.g. to resolve placeholders of secrets
+	ClusterInformationTransform func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error)
+
+	// ClusterNameNormalizer normalizes a cluster name before comparison, e.g. strings.ToLower
+	ClusterNameNormalizer func(clusterName string) string
+
+	// ClusterHealthChecker return whether the cluster is healthy, e.g. from recent replication RPC results
+	ClusterHealthChecker func(clusterName string) bool
+)
+
+// WithPrimaryFailoverOrder returns an Option setting the ordered list of clusters
+// to take over as primary cluster if the configured primary cluster becomes unavailable
+func WithPrimaryFailoverOrder(primaryFailoverOrder []string) Option {
+	return func(m *Metadata) {
+		m.primaryFailoverOrder = primaryFailoverOrder
+	}
+}
+
+// WithManualFailoverGenerationOffset returns an Option setting the number of generations skipped by
+// ManualFailoverVersion, DefaultManualFailoverGenerationOffset is used by default
+func WithManualFailoverGenerationOffset(offset int64) Option {
+	return func(m *Metadata) {
+		m.manualFailoverGenerationOffset = offset
+	}
+}
+
+// WithTopologyChangeSink returns an Option setting the sink receiving topology changes
+func WithTopologyChangeSink(sink TopologyChangeSink) Option {
+	return func(m *Metadata) {
+		m.topologyChangeSink = sink
+	}
+}
+
+// WithTimeSource returns an Option setting the time source, e.g. for timestamping topology changes
+func WithTimeSource(timeSource clock.TimeSource) Option {
+	return func(m *Metadata) {
+		m.timeSource = timeSource
+	}
+}
+
+// WithMetricsClient returns an Option setting the metrics client, used to count unknown failover versions
+func WithMetricsClient(metricsClient metrics.Client) Option {
+	return func(m *Metadata) {
+		m.metricsClient = metricsClient
+	}
+}
+
+// WithUnknownVersionPolicy returns an Option setting the handling of failover versions not belonging to any cluster
+// by ClusterNameForFailoverVersion and ClusterNameForFailoverVersionSafe, UnknownVersionPolicyPanic is used by default
+func WithUnknownVersionPolicy(policy UnknownVersionPolicy) Option {
+	return func(m *Metadata) {
+		m.unknownVersionPolicy = policy
+	}
+}
+
+// WithClusterInformationTransform returns an Option setting the transform applied to each cluster info
+// loaded by NewMetadata, NewValidatedMetadata, NewPersistentMetadata, Refresh and UpdateClusterInformation.
+// The validating entry points validate the transformed cluster group and return transform errors, NewMetadata
+// panics if the transform fails. ApplyClusterPatch and RestoreState do not apply it, their cluster info
+// was already transformed.
+func WithClusterInformationTransform(transform ClusterInformationTransform) Option {
+	return func(m *Metadata) {
+		m.clusterInformationTransform = transform
+	}
+}
+
+// WithDefaultRPCTransport returns an Option setting the RPC transport of clusters without configured RPC transport,
+// tchannel is used by default
+func WithDefaultRPCTransport(transport string) Option {
+	return func(m *Metadata) {
+		m.defaultRPCTransport = transport
+	}
+}
+
+// WithClusterNameNormalizer returns an Option setting the normalization of cluster names compared by IsCurrentCluster,
+// cluster names are compared as is by default
+func WithClusterNameNormalizer(normalizer ClusterNameNormalizer) Option {
+	return func(m *Metadata) {
+		m.clusterNameNormalizer = normalizer
+	}
+}
+
+// WithOnResolveDisabledCluster returns an Option setting the callback invoked when ClusterNameForFailoverVersion
+// resolves a failover version to a disabled cluster, e.g. to monitor legacy data or traffic of drained clusters
+func WithOnResolveDisabledCluster(callback func(clusterName string, failoverVersion int64)) Option {
+	return func(m *Metadata) {
+		m.onResolveDisabledCluster = callback
+	}
+}
+
+// WithTopologyChangeDebounce returns an Option coalescing the changes of rapid UpdateClusterInformation calls,
+// e.g. from config reloads. The changes are reported to the topology change sink once no update happened
+// for the window, by default changes are reported immediately. The window is timed with the time source if it is
+// a clock.TimerSource, see WithTimeSource, and the pending timer is stopped by Close.
+func WithTopologyChangeDebounce(window time.Duration) Option {
+	return func(m *Metadata) {
+		m.topologyChangeDebounce = window
+	}
+}
+
+// WithClusterHealthChecker returns an Option setting the health checker consulted by IsDomainActiveClusterReachable,
+// clusters are considered healthy by default
+func WithClusterHealthChecker(healthChecker ClusterHealthChecker) Option {
+	return func(m *Metadata) {
+		m.healthChecker = healthChecker
+	}
+}
+
+// WithMaxFailoverGeneration returns an Option setting the highest generation of failover versions returned
+// by GetNextFailoverVersionSafe, e.g. to detect failover loops inflating versions. There is no limit by default.
+// GetNextFailoverVersion ignores it, as it cannot return ErrFailoverGenerationExceeded.
+func WithMaxFailoverGeneration(maxGeneration int64) Option {
+	return func(m *Metadata) {
+		m.maxFailoverGeneration = maxGeneration
+	}
+}
+
+// WithRPCAddressChangeCallback returns an Option setting the callback invoked when the RPC address of a cluster
+// changes, e.g. to reconnect client pools. It is invoked for every update of the cluster group, including
+// UpdateClusterInformation, ApplyClusterPatch, Refresh and RestoreState, and is not debounced.
+func WithRPCAddressChangeCallback(callback func(clusterName string, oldAddress string, newAddress string)) Option {
+	return func(m *Metadata) {
+		m.onRPCAddressChange = callback
+	}
+}
+
+// WithAddressResolver returns an Option setting the resolver of the RPC addresses of clusters using dynamic discovery,
+// the static RPC address is used by default
+func WithAddressResolver(resolver AddressResolver) Option {
+	return func(m *Metadata) {
+		m.addressResolver = resolver
+	}
+}
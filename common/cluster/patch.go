@@ -0,0 +1,104 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/uber/cadence/common/config"
+)
+
+type (
+	// ClusterPatch describes targeted changes of the info of a single cluster, nil fields are left unchanged
+	ClusterPatch struct {
+		ClusterName  string
+		Enabled      *bool
+		RPCName      *string
+		RPCAddress   *string
+		RPCTransport *string
+	}
+
+	// rpcAddressChange is a changed RPC address of a cluster, see WithRPCAddressChangeCallback
+	rpcAddressChange struct {
+		clusterName string
+		oldAddress  string
+		newAddress  string
+	}
+)
+
+// ApplyClusterPatch applies the patch to the cluster group, the change is visible to all copies of the Metadata.
+// The patched cluster group is validated like by UpdateClusterInformation, e.g. enabled clusters need an RPC name
+// and address, and the new topology must pass the registered validators, otherwise nothing is changed.
+// The change is reported to the topology change sink, if any, and a changed RPC address to the RPC address
+// change callback, if any.
+func (m Metadata) ApplyClusterPatch(patch ClusterPatch) error {
+	m.topology.Lock()
+	oldTopology := m.topology.current
+	info, ok := oldTopology.allClusters[patch.ClusterName]
+	if !ok {
+		m.topology.Unlock()
+		return fmt.Errorf("cluster %v is not specified in the cluster group", patch.ClusterName)
+	}
+
+	if patch.Enabled != nil {
+		info.Enabled = *patch.Enabled
+	}
+	if patch.RPCName != nil {
+		info.RPCName = *patch.RPCName
+	}
+	if patch.RPCAddress != nil {
+		info.RPCAddress = *patch.RPCAddress
+	}
+	if patch.RPCTransport != nil {
+		info.RPCTransport = *patch.RPCTransport
+	}
+
+	clusterGroup := make(map[string]config.ClusterInformation, len(oldTopology.allClusters))
+	for clusterName, clusterInfo := range oldTopology.allClusters {
+		clusterGroup[clusterName] = clusterInfo
+	}
+	clusterGroup[patch.ClusterName] = info
+	newTopology := newClusterTopology(oldTopology.primaryClusterName, oldTopology.currentClusterName, clusterGroup)
+	err := m.validateTopology(newTopology.primaryClusterName, newTopology.allClusters)
+	if err == nil {
+		candidate := m
+		candidate.topology = &sharedTopology{current: newTopology}
+		err = runMetadataValidators(candidate)
+	}
+	if err != nil {
+		m.topology.Unlock()
+		return err
+	}
+	events, addressChanges := m.replaceTopologyLocked(newTopology)
+	m.topology.Unlock()
+
+	m.notifyRPCAddressChanges(addressChanges)
+	m.recordChanges(events)
+	return nil
+}
+
+// UpdateClusterRPCAddress updates the RPC address of the cluster with ApplyClusterPatch, e.g. when its frontend moves.
+func (m Metadata) UpdateClusterRPCAddress(clusterName string, newAddress string) error {
+	if len(newAddress) == 0 {
+		return fmt.Errorf("cluster %v: rpc address is empty", clusterName)
+	}
+	return m.ApplyClusterPatch(ClusterPatch{ClusterName: clusterName, RPCAddress: &newAddress})
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/clock"
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataApplyClusterPatch(t *testing.T) {
+	sink := &recordingTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+	)
+	enabled, disabled := true, false
+	rpcName, rpcAddress, rpcTransport := "cadence-frontend", "127.0.0.1:9833", "grpc"
+
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{
+		ClusterName:  TestDisabledClusterName,
+		Enabled:      &enabled,
+		RPCName:      &rpcName,
+		RPCAddress:   &rpcAddress,
+		RPCTransport: &rpcTransport,
+	}))
+	info := metadata.GetAllClusterInfo()[TestDisabledClusterName]
+	assert.True(t, info.Enabled)
+	assert.Equal(t, rpcAddress, info.RPCAddress)
+	assert.Equal(t, TestDisabledClusterInitialFailoverVersion, info.InitialFailoverVersion)
+	assert.Contains(t, metadata.GetRemoteClusterInfo(), TestDisabledClusterName)
+	// the rpc settings changed along with enabled are reported as an update
+	assert.Equal(t, TopologyChangeTypeClusterEnabled, sink.events[len(sink.events)-2].Type)
+	assert.Equal(t, TopologyChangeTypeClusterUpdated, sink.events[len(sink.events)-1].Type)
+	assert.Equal(t, TestAllClusterInfo[TestDisabledClusterName], sink.events[len(sink.events)-1].OldValue)
+	assert.Equal(t, info, sink.events[len(sink.events)-1].NewValue)
+
+	newAddress := "127.0.0.1:10833"
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestAlternativeClusterName, RPCAddress: &newAddress}))
+	assert.Equal(t, newAddress, metadata.GetAllClusterInfo()[TestAlternativeClusterName].RPCAddress)
+	assert.Equal(t, TopologyChangeTypeClusterUpdated, sink.events[len(sink.events)-1].Type)
+
+	// an empty rpc transport uses the default, like in the config validation
+	emptyTransport := ""
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestAlternativeClusterName, RPCTransport: &emptyTransport}))
+	transport, err := metadata.GetClusterRPCTransport(TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, "tchannel", transport)
+
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestDisabledClusterName, Enabled: &disabled}))
+	assert.NotContains(t, metadata.GetEnabledClusterInfo(), TestDisabledClusterName)
+	assert.Equal(t, TopologyChangeTypeClusterDisabled, sink.events[len(sink.events)-1].Type)
+
+	// invalid patches leave the state unchanged
+	before := metadata.GetAllClusterInfo()
+	numEvents := len(sink.events)
+	assert.EqualError(t,
+		metadata.ApplyClusterPatch(ClusterPatch{ClusterName: "unknown", Enabled: &enabled}),
+		"cluster unknown is not specified in the cluster group",
+	)
+	emptyAddress, invalidTransport := "", "http"
+	assert.EqualError(t,
+		metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestAlternativeClusterName, RPCAddress: &emptyAddress, RPCTransport: &invalidTransport}),
+		"cluster standby: rpc name / address is empty; cluster standby: rpc transport must tchannel or grpc",
+	)
+	assert.Equal(t, before, metadata.GetAllClusterInfo())
+	assert.Len(t, sink.events, numEvents)
+}
+
+func TestMetadataApplyClusterPatchDisabledReplicaCluster(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName: func() config.ClusterInformation {
+			info := TestAllClusterInfo[TestCurrentClusterName]
+			info.ReplicaClusters = []string{TestAlternativeClusterName}
+			return info
+		}(),
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+
+	assert.EqualError(t,
+		metadata.SetClusterEnabled(TestAlternativeClusterName, false),
+		"cluster active: replica cluster standby is not enabled",
+	)
+	assert.True(t, metadata.GetAllClusterInfo()[TestAlternativeClusterName].Enabled)
+
+	disabled := clusterGroup[TestAlternativeClusterName]
+	disabled.Enabled = false
+	assert.EqualError(t,
+		metadata.UpdateClusterInformation(TestCurrentClusterName, map[string]config.ClusterInformation{
+			TestCurrentClusterName:     clusterGroup[TestCurrentClusterName],
+			TestAlternativeClusterName: disabled,
+		}),
+		"cluster active: replica cluster standby is not enabled",
+	)
+
+	allowed := clusterGroup[TestCurrentClusterName]
+	allowed.AllowDisabledReplicaClusters = true
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, map[string]config.ClusterInformation{
+		TestCurrentClusterName:     allowed,
+		TestAlternativeClusterName: disabled,
+	}))
+	assert.NotContains(t, metadata.GetEnabledClusterInfo(), TestAlternativeClusterName)
+}
+
+func TestMetadataUpdateClusterRPCAddress(t *testing.T) {
+	type addressChange struct {
+		clusterName, oldAddress, newAddress string
+	}
+	var changes []addressChange
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestAlternativeClusterName,
+		TestAllClusterInfo,
+		WithRPCAddressChangeCallback(func(clusterName string, oldAddress string, newAddress string) {
+			changes = append(changes, addressChange{clusterName, oldAddress, newAddress})
+		}),
+	)
+
+	newAddress := "127.0.0.1:9104"
+	assert.NoError(t, metadata.UpdateClusterRPCAddress(TestCurrentClusterName, newAddress))
+	assert.Equal(t, newAddress, metadata.GetAllClusterInfo()[TestCurrentClusterName].RPCAddress)
+	assert.Equal(t, []addressChange{{TestCurrentClusterName, TestCurrentClusterFrontendAddress, newAddress}}, changes)
+
+	// unchanged addresses and invalid updates do not invoke the callback
+	assert.NoError(t, metadata.UpdateClusterRPCAddress(TestCurrentClusterName, newAddress))
+	assert.EqualError(t, metadata.UpdateClusterRPCAddress("unknown", newAddress), "cluster unknown is not specified in the cluster group")
+	assert.EqualError(t, metadata.UpdateClusterRPCAddress(TestCurrentClusterName, ""), "cluster active: rpc address is empty")
+	assert.Len(t, changes, 1)
+	assert.Equal(t, newAddress, metadata.GetAllClusterInfo()[TestCurrentClusterName].RPCAddress)
+
+	// the callback is invoked for addresses changed by any update of the cluster group
+	state := metadata.SaveState()
+	patchedAddress := "127.0.0.1:10104"
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestAlternativeClusterName, RPCAddress: &patchedAddress}))
+	clusterGroup := metadata.ToClusterGroup()
+	active := clusterGroup[TestCurrentClusterName]
+	active.RPCAddress = TestCurrentClusterFrontendAddress
+	clusterGroup[TestCurrentClusterName] = active
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	metadata.RestoreState(state)
+	assert.Equal(t, []addressChange{
+		{TestCurrentClusterName, TestCurrentClusterFrontendAddress, newAddress},
+		{TestAlternativeClusterName, TestAlternativeClusterFrontendAddress, patchedAddress},
+		{TestCurrentClusterName, newAddress, TestCurrentClusterFrontendAddress},
+		{TestCurrentClusterName, TestCurrentClusterFrontendAddress, newAddress},
+		{TestAlternativeClusterName, patchedAddress, TestAlternativeClusterFrontendAddress},
+	}, changes)
+}
+
+func TestMetadataRPCAddressChangeCallbackNotDebounced(t *testing.T) {
+	var changedClusters []string
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(&recordingTopologyChangeSink{}),
+		WithTopologyChangeDebounce(time.Minute),
+		WithTimeSource(clock.NewEventTimeSource()),
+		WithRPCAddressChangeCallback(func(clusterName string, oldAddress string, newAddress string) {
+			changedClusters = append(changedClusters, clusterName)
+		}),
+	)
+	defer metadata.Close()
+
+	clusterGroup := metadata.ToClusterGroup()
+	standby := clusterGroup[TestAlternativeClusterName]
+	standby.RPCAddress = "127.0.0.1:10104"
+	clusterGroup[TestAlternativeClusterName] = standby
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	assert.Equal(t, []string{TestAlternativeClusterName}, changedClusters)
+}
+
+func TestMetadataRuntimeUpdatesRejectSharedRPCAddress(t *testing.T) {
+	clusterGroup := func() map[string]config.ClusterInformation {
+		disabled := TestAllClusterInfo[TestAlternativeClusterName]
+		disabled.Enabled = false
+		disabled.InitialFailoverVersion = TestDisabledClusterInitialFailoverVersion
+		return map[string]config.ClusterInformation{
+			TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+			TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+			// a disabled copy of the alternative cluster, sharing its rpc address
+			TestDisabledClusterName: disabled,
+		}
+	}
+	sharedAddressErr := "clusters disabled, standby share the rpc address 127.0.0.1:8104"
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup())
+	before := metadata.GetAllClusterInfo()
+
+	assert.EqualError(t, metadata.SetClusterEnabled(TestDisabledClusterName, true), sharedAddressErr)
+
+	enabled := true
+	assert.EqualError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestDisabledClusterName, Enabled: &enabled}), sharedAddressErr)
+
+	assert.EqualError(t,
+		metadata.UpdateClusterRPCAddress(TestCurrentClusterName, TestAlternativeClusterFrontendAddress),
+		"clusters active, standby share the rpc address 127.0.0.1:8104",
+	)
+
+	updatedGroup := clusterGroup()
+	disabled := updatedGroup[TestDisabledClusterName]
+	disabled.Enabled = true
+	updatedGroup[TestDisabledClusterName] = disabled
+	assert.EqualError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, updatedGroup), sharedAddressErr)
+
+	assert.Equal(t, before, metadata.GetAllClusterInfo())
+
+	// a distinct address makes enabling the cluster valid
+	newAddress := "127.0.0.1:9104"
+	assert.NoError(t, metadata.ApplyClusterPatch(ClusterPatch{ClusterName: TestDisabledClusterName, Enabled: &enabled, RPCAddress: &newAddress}))
+	assert.Contains(t, metadata.GetEnabledClusterInfo(), TestDisabledClusterName)
+}
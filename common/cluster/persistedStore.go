@@ -0,0 +1,89 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uber/cadence/common/config"
+)
+
+type (
+	// PersistedClusterStore is a persisted source of the cluster group config, e.g. a database table
+	PersistedClusterStore interface {
+		GetClusterGroupMetadata(ctx context.Context) (*config.ClusterGroupMetadata, error)
+	}
+)
+
+// NewPersistentMetadata creates a new instance of Metadata from the cluster group config read from the store,
+// the Metadata can then be refreshed from the store with Refresh
+func NewPersistentMetadata(ctx context.Context, store PersistedClusterStore, opts ...Option) (Metadata, error) {
+	clusterGroupMetadata, err := store.GetClusterGroupMetadata(ctx)
+	if err != nil {
+		return Metadata{}, err
+	}
+	opts = append(opts, func(m *Metadata) {
+		m.store = store
+	})
+	return NewValidatedMetadata(clusterGroupMetadata, opts...)
+}
+
+// Refresh re-reads the cluster group config from the persisted store and applies the primary cluster
+// and cluster group like UpdateClusterInformation, the cluster information transform, if any, is applied before
+// the read config is validated. The failover version increment and the current cluster cannot change on refresh.
+func (m Metadata) Refresh(ctx context.Context) error {
+	if m.store == nil {
+		return errors.New("cluster metadata is not backed by a persisted store")
+	}
+
+	clusterGroupMetadata, err := m.store.GetClusterGroupMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	if clusterGroupMetadata == nil {
+		return clusterGroupMetadata.Validate()
+	}
+	transformed := *clusterGroupMetadata
+	transformed.ClusterGroup, err = m.transformClusterGroup(clusterGroupMetadata.ClusterGroup)
+	if err != nil {
+		return err
+	}
+	if err := transformed.Validate(); err != nil {
+		return err
+	}
+	if clusterGroupMetadata.FailoverVersionIncrement != m.failoverVersionIncrement {
+		return fmt.Errorf(
+			"failover version increment cannot change on refresh: %v vs %v",
+			m.failoverVersionIncrement,
+			clusterGroupMetadata.FailoverVersionIncrement,
+		)
+	}
+	if clusterGroupMetadata.CurrentClusterName != m.currentClusterName {
+		return fmt.Errorf(
+			"current cluster cannot change on refresh: %v vs %v",
+			m.currentClusterName,
+			clusterGroupMetadata.CurrentClusterName,
+		)
+	}
+	return m.replaceClusterGroup(transformed.PrimaryClusterName, transformed.ClusterGroup)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+type fakePersistedClusterStore struct {
+	clusterGroupMetadata *config.ClusterGroupMetadata
+	err                  error
+}
+
+func (s *fakePersistedClusterStore) GetClusterGroupMetadata(ctx context.Context) (*config.ClusterGroupMetadata, error) {
+	return s.clusterGroupMetadata, s.err
+}
+
+func TestPersistentMetadata(t *testing.T) {
+	store := &fakePersistedClusterStore{
+		clusterGroupMetadata: &config.ClusterGroupMetadata{
+			FailoverVersionIncrement: TestFailoverVersionIncrement,
+			PrimaryClusterName:       TestCurrentClusterName,
+			CurrentClusterName:       TestCurrentClusterName,
+			ClusterGroup:             TestSingleDCClusterInfo,
+		},
+	}
+
+	metadata, err := NewPersistentMetadata(context.Background(), store)
+	require.NoError(t, err)
+	assert.True(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, TestSingleDCClusterInfo, metadata.GetAllClusterInfo())
+	assert.Empty(t, metadata.GetRemoteClusterInfo())
+
+	store.clusterGroupMetadata = &config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestAlternativeClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup: map[string]config.ClusterInformation{
+			TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+			TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		},
+	}
+	require.NoError(t, metadata.Refresh(context.Background()))
+	assert.False(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, store.clusterGroupMetadata.ClusterGroup, metadata.GetAllClusterInfo())
+	assert.Len(t, metadata.GetRemoteClusterInfo(), 1)
+
+	store.err = errors.New("store unavailable")
+	assert.EqualError(t, metadata.Refresh(context.Background()), "store unavailable")
+
+	store.err = nil
+	store.clusterGroupMetadata.FailoverVersionIncrement = 100
+	assert.EqualError(t, metadata.Refresh(context.Background()), "failover version increment cannot change on refresh: 10 vs 100")
+	assert.False(t, metadata.IsPrimaryCluster())
+}
+
+func TestRefreshWithoutStore(t *testing.T) {
+	assert.Error(t, GetTestClusterMetadata(true).Refresh(context.Background()))
+}
+
+func TestRefreshClusterInformationTransform(t *testing.T) {
+	// the stored rpc address of the standby cluster is a placeholder resolved by the transform
+	standbyInfo := TestAllClusterInfo[TestAlternativeClusterName]
+	standbyInfo.RPCAddress = ""
+	store := &fakePersistedClusterStore{
+		clusterGroupMetadata: &config.ClusterGroupMetadata{
+			FailoverVersionIncrement: TestFailoverVersionIncrement,
+			PrimaryClusterName:       TestCurrentClusterName,
+			CurrentClusterName:       TestCurrentClusterName,
+			ClusterGroup: map[string]config.ClusterInformation{
+				TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+				TestAlternativeClusterName: standbyInfo,
+			},
+		},
+	}
+	resolved := true
+	resolveRPCAddress := func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error) {
+		if info.RPCAddress == "" && resolved {
+			info.RPCAddress = clusterName + ".example.com:7833"
+		}
+		return info, nil
+	}
+
+	metadata, err := NewPersistentMetadata(context.Background(), store, WithClusterInformationTransform(resolveRPCAddress))
+	require.NoError(t, err)
+	assert.Equal(t, "standby.example.com:7833", metadata.GetAllClusterInfo()[TestAlternativeClusterName].RPCAddress)
+
+	store.clusterGroupMetadata.PrimaryClusterName = TestAlternativeClusterName
+	require.NoError(t, metadata.Refresh(context.Background()))
+	assert.False(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, "standby.example.com:7833", metadata.GetAllClusterInfo()[TestAlternativeClusterName].RPCAddress)
+
+	// the transformed config is validated
+	resolved = false
+	store.clusterGroupMetadata.PrimaryClusterName = TestCurrentClusterName
+	assert.EqualError(t, metadata.Refresh(context.Background()), "cluster standby: rpc name / address is empty")
+	assert.False(t, metadata.IsPrimaryCluster())
+}
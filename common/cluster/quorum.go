@@ -0,0 +1,45 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import "github.com/uber/cadence/common/config"
+
+// QuorumClusters return the sorted names of the clusters taking part in quorum based failover decisions
+func (m Metadata) QuorumClusters() []string {
+	allClusters := m.getTopology().allClusters
+	quorumClusters := make(map[string]config.ClusterInformation)
+	for clusterName, info := range allClusters {
+		if info.QuorumMember {
+			quorumClusters[clusterName] = info
+		}
+	}
+	return sortedClusterNames(quorumClusters)
+}
+
+// QuorumSize return the number of quorum members which have to agree on a failover decision,
+// i.e. the majority of the quorum members, 0 if there is no quorum member
+func (m Metadata) QuorumSize() int {
+	members := len(m.QuorumClusters())
+	if members == 0 {
+		return 0
+	}
+	return members/2 + 1
+}
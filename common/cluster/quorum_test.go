@@ -0,0 +1,90 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataQuorum(t *testing.T) {
+	tests := []struct {
+		members int
+		size    int
+	}{
+		{members: 0, size: 0},
+		{members: 1, size: 1},
+		{members: 2, size: 2},
+		{members: 3, size: 2},
+		{members: 4, size: 3},
+		{members: 5, size: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v members", tt.members), func(t *testing.T) {
+			clusterGroup := map[string]config.ClusterInformation{
+				"observer": {Enabled: true, InitialFailoverVersion: 9},
+			}
+			expected := []string{}
+			for i := 0; i < tt.members; i++ {
+				clusterName := fmt.Sprintf("cluster-%v", i)
+				clusterGroup[clusterName] = config.ClusterInformation{Enabled: true, InitialFailoverVersion: int64(i), QuorumMember: true}
+				expected = append(expected, clusterName)
+			}
+			metadata := NewMetadata(TestFailoverVersionIncrement, "observer", "observer", clusterGroup)
+
+			assert.Equal(t, expected, metadata.QuorumClusters())
+			assert.Equal(t, tt.size, metadata.QuorumSize())
+		})
+	}
+}
+
+func TestMetadataQuorumMemberMustBeEnabled(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName: TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: func() config.ClusterInformation {
+			info := TestAllClusterInfo[TestAlternativeClusterName]
+			info.QuorumMember = true
+			return info
+		}(),
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+	assert.Equal(t, []string{TestAlternativeClusterName}, metadata.QuorumClusters())
+
+	assert.EqualError(t,
+		metadata.SetClusterEnabled(TestAlternativeClusterName, false),
+		"cluster standby: quorum member is not enabled",
+	)
+
+	disabled := clusterGroup[TestAlternativeClusterName]
+	disabled.Enabled = false
+	assert.EqualError(t,
+		metadata.UpdateClusterInformation(TestCurrentClusterName, map[string]config.ClusterInformation{
+			TestCurrentClusterName:     clusterGroup[TestCurrentClusterName],
+			TestAlternativeClusterName: disabled,
+		}),
+		"cluster standby: quorum member is not enabled",
+	)
+	assert.True(t, metadata.GetAllClusterInfo()[TestAlternativeClusterName].Enabled)
+}
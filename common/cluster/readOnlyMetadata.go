@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"github.com/uber/cadence/common/config"
+)
+
+type (
+	// ReadOnlyMetadata is the read only view of Metadata, without the methods changing the topology
+	// or the circuit, replication and handshake state. Consumers only reading the cluster metadata
+	// should accept it instead of Metadata.
+	// It is limited to what consumers use, methods should only be added once a consumer needs them.
+	// The cluster group is exposed as copies, GetAllClusterInfo, GetEnabledClusterInfo and GetRemoteClusterInfo
+	// return the internal maps and are left out.
+	ReadOnlyMetadata interface {
+		GetCurrentClusterName() string
+		IsCurrentCluster(clusterName string) bool
+		IsPrimaryCluster() bool
+		GetNextFailoverVersion(cluster string, currentFailoverVersion int64) int64
+		ClusterNameForFailoverVersion(failoverVersion int64) string
+		IsVersionFromSameCluster(version1 int64, version2 int64) bool
+		AllClustersOrdered() []NamedClusterInformation
+		ToClusterGroup() map[string]config.ClusterInformation
+	}
+)
+
+var _ ReadOnlyMetadata = Metadata{}
@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyMetadata(t *testing.T) {
+	var readOnly ReadOnlyMetadata = GetTestClusterMetadata(true)
+	assert.Equal(t, TestCurrentClusterName, readOnly.GetCurrentClusterName())
+	assert.True(t, readOnly.IsPrimaryCluster())
+
+	readOnlyType := reflect.TypeOf((*ReadOnlyMetadata)(nil)).Elem()
+	for _, mutator := range []string{
+		"UpdateClusterInformation",
+		"SetClusterEnabled",
+		"PromotePrimary",
+		"ApplyClusterPatch",
+		"Refresh",
+		"SetClusterCircuitOpen",
+		"SetReplicationPaused",
+		"RecordRemoteHandshake",
+		// these return the internal maps
+		"GetAllClusterInfo",
+		"GetEnabledClusterInfo",
+		"GetRemoteClusterInfo",
+	} {
+		_, ok := readOnlyType.MethodByName(mutator)
+		assert.False(t, ok, "read only metadata exposes %v", mutator)
+		_, ok = reflect.TypeOf(Metadata{}).MethodByName(mutator)
+		assert.True(t, ok, "metadata lacks %v", mutator)
+	}
+}
+
+func TestReadOnlyMetadataClusterGroupCopies(t *testing.T) {
+	metadata := GetTestClusterMetadata(true)
+	var readOnly ReadOnlyMetadata = metadata
+
+	clusterGroup := readOnly.ToClusterGroup()
+	delete(clusterGroup, TestAlternativeClusterName)
+	clusters := readOnly.AllClustersOrdered()
+	clusters[0].Info.RPCAddress = "127.0.0.1:1"
+
+	assert.Equal(t, TestAllClusterInfo, metadata.GetAllClusterInfo())
+}
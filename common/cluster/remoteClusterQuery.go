@@ -0,0 +1,75 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import "github.com/uber/cadence/common/config"
+
+type (
+	// RemoteClusterQuery filters the enabled remote clusters by multiple criteria, all criteria must match
+	RemoteClusterQuery struct {
+		metadata     Metadata
+		regions      []string
+		capabilities []string
+	}
+)
+
+// RemoteClusterQuery return a query over the enabled remote clusters
+func (m Metadata) RemoteClusterQuery() RemoteClusterQuery {
+	return RemoteClusterQuery{metadata: m}
+}
+
+// InRegion return a query additionally requiring the region tag of the clusters to be the given region
+func (q RemoteClusterQuery) InRegion(region string) RemoteClusterQuery {
+	q.regions = append(q.regions[:len(q.regions):len(q.regions)], region)
+	return q
+}
+
+// WithCapability return a query additionally requiring the clusters to support the given capability
+func (q RemoteClusterQuery) WithCapability(capability string) RemoteClusterQuery {
+	q.capabilities = append(q.capabilities[:len(q.capabilities):len(q.capabilities)], capability)
+	return q
+}
+
+// Names return the sorted names of the enabled remote clusters matching the query
+func (q RemoteClusterQuery) Names() []string {
+	var clusterNames []string
+	remoteClusters := q.metadata.GetRemoteClusterInfo()
+	for _, clusterName := range sortedClusterNames(remoteClusters) {
+		if q.matches(remoteClusters[clusterName]) {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	return clusterNames
+}
+
+func (q RemoteClusterQuery) matches(info config.ClusterInformation) bool {
+	for _, region := range q.regions {
+		if info.Tags[RegionTag] != region {
+			return false
+		}
+	}
+	for _, capability := range q.capabilities {
+		if !hasCapability(info, capability) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataRemoteClusterQuery(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":    {Enabled: true, InitialFailoverVersion: 0, Tags: map[string]string{RegionTag: "x"}, Capabilities: []string{"y"}},
+		"x-y":      {Enabled: true, InitialFailoverVersion: 1, Tags: map[string]string{RegionTag: "x"}, Capabilities: []string{"y", "z"}},
+		"x":        {Enabled: true, InitialFailoverVersion: 2, Tags: map[string]string{RegionTag: "x"}},
+		"w-y":      {Enabled: true, InitialFailoverVersion: 3, Tags: map[string]string{RegionTag: "w"}, Capabilities: []string{"y"}},
+		"disabled": {Enabled: false, InitialFailoverVersion: 4, Tags: map[string]string{RegionTag: "x"}, Capabilities: []string{"y"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "local", "local", clusterGroup)
+
+	assert.Equal(t, []string{"w-y", "x", "x-y"}, metadata.RemoteClusterQuery().Names())
+	assert.Equal(t, []string{"x", "x-y"}, metadata.RemoteClusterQuery().InRegion("x").Names())
+	assert.Equal(t, []string{"w-y", "x-y"}, metadata.RemoteClusterQuery().WithCapability("y").Names())
+	assert.Equal(t, []string{"x-y"}, metadata.RemoteClusterQuery().InRegion("x").WithCapability("y").Names())
+	assert.Equal(t, []string{"x-y"}, metadata.RemoteClusterQuery().WithCapability("y").WithCapability("z").Names())
+	assert.Empty(t, metadata.RemoteClusterQuery().InRegion("w").WithCapability("z").Names())
+
+	// queries are immutable, derived queries do not affect each other
+	inX := metadata.RemoteClusterQuery().InRegion("x")
+	withY := inX.WithCapability("y")
+	withZ := inX.WithCapability("missing")
+	assert.Equal(t, []string{"x-y"}, withY.Names())
+	assert.Empty(t, withZ.Names())
+	assert.Equal(t, []string{"x", "x-y"}, inX.Names())
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/cadence/common/config"
+)
+
+const (
+	// DefaultMaxReplicationLag is the max replication lag of clusters without configured max replication lag
+	DefaultMaxReplicationLag = 5 * time.Minute
+)
+
+const (
+	// ReplicationDirNone indicates no replication between the two clusters
+	ReplicationDirNone ReplicationDir = iota
+	// ReplicationDirForward indicates replication from the first to the second cluster only
+	ReplicationDirForward
+	// ReplicationDirBackward indicates replication from the second to the first cluster only
+	ReplicationDirBackward
+	// ReplicationDirBoth indicates replication in both directions
+	ReplicationDirBoth
+)
+
+type (
+	// ReplicationDir is the direction of replication between two clusters
+	ReplicationDir int
+
+	// ReplicationTarget is a destination cluster of a replicated event, see ReplicationPlanForVersion
+	ReplicationTarget struct {
+		Cluster         string
+		ExpectedVersion int64
+	}
+)
+
+// String returns the name of the replication direction
+func (d ReplicationDir) String() string {
+	switch d {
+	case ReplicationDirNone:
+		return "None"
+	case ReplicationDirForward:
+		return "Forward"
+	case ReplicationDirBackward:
+		return "Backward"
+	case ReplicationDirBoth:
+		return "Both"
+	default:
+		return fmt.Sprintf("ReplicationDir(%d)", int(d))
+	}
+}
+
+// ReplicationDirection return the direction of replication between the two clusters based on their replica clusters,
+// a cluster without replica clusters replicates to all other clusters
+func (m Metadata) ReplicationDirection(from, to string) (ReplicationDir, error) {
+	allClusters := m.GetAllClusterInfo()
+	fromInfo, fromOK := allClusters[from]
+	toInfo, toOK := allClusters[to]
+	var unknown []string
+	if !fromOK {
+		unknown = append(unknown, from)
+	}
+	if !toOK && to != from {
+		unknown = append(unknown, to)
+	}
+	if len(unknown) > 0 {
+		return ReplicationDirNone, fmt.Errorf("unknown cluster name: %v", strings.Join(unknown, ", "))
+	}
+	if from == to {
+		return ReplicationDirNone, nil
+	}
+
+	forward := replicatesTo(fromInfo, to)
+	backward := replicatesTo(toInfo, from)
+	switch {
+	case forward && backward:
+		return ReplicationDirBoth, nil
+	case forward:
+		return ReplicationDirForward, nil
+	case backward:
+		return ReplicationDirBackward, nil
+	default:
+		return ReplicationDirNone, nil
+	}
+}
+
+// RemoteClustersByPriority return the names of the enabled remote clusters sorted by ascending replication priority,
+// then by name. Clusters without replication priority are sorted last.
+func (m Metadata) RemoteClustersByPriority() []string {
+	remoteClusters := m.GetRemoteClusterInfo()
+	clusterNames := sortedClusterNames(remoteClusters)
+	sort.SliceStable(clusterNames, func(i, j int) bool {
+		left := remoteClusters[clusterNames[i]].ReplicationPriority
+		right := remoteClusters[clusterNames[j]].ReplicationPriority
+		if left == 0 || right == 0 {
+			return right == 0 && left != 0
+		}
+		return left < right
+	})
+	return clusterNames
+}
+
+// RemoteClustersByLag return the names of the enabled remote clusters sorted by descending replication lag,
+// i.e. the most behind cluster first, then as RemoteClustersByPriority. Clusters without lag are sorted last,
+// unknown cluster names of the lag are ignored.
+func (m Metadata) RemoteClustersByLag(lag map[string]time.Duration) []string {
+	clusterNames := m.RemoteClustersByPriority()
+	sort.SliceStable(clusterNames, func(i, j int) bool {
+		return lag[clusterNames[i]] > lag[clusterNames[j]]
+	})
+	return clusterNames
+}
+
+// ClusterForResidue return the name of the cluster owning the failover version residue, if any
+func (m Metadata) ClusterForResidue(residue int64) (string, bool) {
+	if residue < 0 || residue >= m.failoverVersionIncrement {
+		return "", false
+	}
+	clusterName, ok := m.getTopology().versionToClusterName[residue]
+	return clusterName, ok
+}
+
+// DependentsOfResidue return the sorted names of the clusters replicating to or from the cluster owning
+// the failover version residue, i.e. the clusters to check before reclaiming the residue.
+// No cluster is returned for residues not owned by any cluster.
+func (m Metadata) DependentsOfResidue(residue int64) []string {
+	owner, ok := m.ClusterForResidue(residue)
+	if !ok {
+		return nil
+	}
+
+	allClusters := m.getTopology().allClusters
+	var dependents []string
+	for _, clusterName := range sortedClusterNames(allClusters) {
+		if clusterName == owner {
+			continue
+		}
+		if replicatesTo(allClusters[owner], clusterName) || replicatesTo(allClusters[clusterName], owner) {
+			dependents = append(dependents, clusterName)
+		}
+	}
+	return dependents
+}
+
+// GetClusterMaxReplicationLag return the replication lag of the cluster to alert on,
+// DefaultMaxReplicationLag if not configured
+func (m Metadata) GetClusterMaxReplicationLag(clusterName string) (time.Duration, error) {
+	info, ok := m.getTopology().allClusters[clusterName]
+	if !ok {
+		return 0, fmt.Errorf("unknown cluster name: %v", clusterName)
+	}
+	if info.MaxReplicationLag == 0 {
+		return DefaultMaxReplicationLag, nil
+	}
+	return info.MaxReplicationLag, nil
+}
+
+// IsReplicationAcyclic return whether the replication between the enabled clusters has no cycle, see ReplicationCycles
+func (m Metadata) IsReplicationAcyclic() bool {
+	return len(m.ReplicationCycles()) == 0
+}
+
+// ReplicationCycles return the cycles of the replication between the enabled clusters, based on their replica clusters.
+// Each cycle is the sorted names of the clusters replicating to each other directly or indirectly, i.e. a strongly
+// connected component of the replication graph, and the cycles are sorted by their first cluster name.
+// Clusters without replica clusters replicate to all other clusters, so they are in a cycle with any other cluster.
+func (m Metadata) ReplicationCycles() [][]string {
+	enabledClusters := m.GetEnabledClusterInfo()
+	clusterNames := sortedClusterNames(enabledClusters)
+
+	// Tarjan's strongly connected components algorithm
+	index := make(map[string]int, len(clusterNames))
+	lowLink := make(map[string]int, len(clusterNames))
+	onStack := make(map[string]bool, len(clusterNames))
+	var stack []string
+	var cycles [][]string
+	var visit func(clusterName string)
+	visit = func(clusterName string) {
+		index[clusterName] = len(index)
+		lowLink[clusterName] = index[clusterName]
+		stack = append(stack, clusterName)
+		onStack[clusterName] = true
+
+		for _, replicaCluster := range clusterNames {
+			if replicaCluster == clusterName || !replicatesTo(enabledClusters[clusterName], replicaCluster) {
+				continue
+			}
+			if _, ok := index[replicaCluster]; !ok {
+				visit(replicaCluster)
+				if lowLink[replicaCluster] < lowLink[clusterName] {
+					lowLink[clusterName] = lowLink[replicaCluster]
+				}
+			} else if onStack[replicaCluster] && index[replicaCluster] < lowLink[clusterName] {
+				lowLink[clusterName] = index[replicaCluster]
+			}
+		}
+
+		if lowLink[clusterName] != index[clusterName] {
+			return
+		}
+		var component []string
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[member] = false
+			component = append(component, member)
+			if member == clusterName {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, clusterName := range clusterNames {
+		if _, ok := index[clusterName]; !ok {
+			visit(clusterName)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// ReplicationPlanForVersion returns the destination clusters of an event stamped with the failover version,
+// i.e. the enabled remote clusters the current cluster replicates to sorted by name, each with its failover version
+// at the generation of the event. It fails for failover versions not belonging to the current cluster.
+func (m Metadata) ReplicationPlanForVersion(version int64) ([]ReplicationTarget, error) {
+	generation, err := m.CurrentClusterGeneration(version)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := m.getTopology()
+	currentInfo := topology.allClusters[m.currentClusterName]
+	remoteClusters := topology.getRemoteClusters()
+	var targets []ReplicationTarget
+	for _, clusterName := range sortedClusterNames(remoteClusters) {
+		if !replicatesTo(currentInfo, clusterName) {
+			continue
+		}
+		targets = append(targets, ReplicationTarget{
+			Cluster:         clusterName,
+			ExpectedVersion: generation*m.failoverVersionIncrement + remoteClusters[clusterName].InitialFailoverVersion,
+		})
+	}
+	return targets, nil
+}
+
+func replicatesTo(info config.ClusterInformation, clusterName string) bool {
+	if len(info.ReplicaClusters) == 0 {
+		return true
+	}
+	for _, replicaCluster := range info.ReplicaClusters {
+		if replicaCluster == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// OrphanedClusters return the sorted names of the enabled clusters which neither replicate to nor receive from
+// any other enabled cluster, which is likely a misconfiguration. Single cluster deployments have no orphans.
+func (m Metadata) OrphanedClusters() []string {
+	enabledClusters := m.GetEnabledClusterInfo()
+	if len(enabledClusters) < 2 {
+		return nil
+	}
+
+	connected := make(map[string]struct{}, len(enabledClusters))
+	for from, info := range enabledClusters {
+		for to := range enabledClusters {
+			if from != to && replicatesTo(info, to) {
+				connected[from] = struct{}{}
+				connected[to] = struct{}{}
+			}
+		}
+	}
+
+	var orphans []string
+	for _, clusterName := range sortedClusterNames(enabledClusters) {
+		if _, ok := connected[clusterName]; !ok {
+			orphans = append(orphans, clusterName)
+		}
+	}
+	return orphans
+}
+
+// IsPassiveCluster return whether the current cluster is a passive standby,
+// i.e. it is not the primary cluster and the enabled primary cluster replicates to it
+func (m Metadata) IsPassiveCluster() bool {
+	topology := m.getTopology()
+	if topology.primaryClusterName == m.currentClusterName {
+		return false
+	}
+	primaryInfo, ok := topology.allClusters[topology.primaryClusterName]
+	return ok && primaryInfo.Enabled && replicatesTo(primaryInfo, m.currentClusterName)
+}
+
+// ActiveClusterName return the name of the primary cluster, e.g. where a passive cluster forwards writes to
+func (m Metadata) ActiveClusterName() string {
+	return m.getTopology().primaryClusterName
+}
+
+// ToDOT return the replication topology as a Graphviz digraph. The primary cluster is drawn as a double circle,
+// the current cluster in bold and disabled clusters dashed, replication between enabled clusters is drawn as edges.
+func (m Metadata) ToDOT() string {
+	topology := m.getTopology()
+	clusterNames := sortedClusterNames(topology.allClusters)
+
+	var builder strings.Builder
+	builder.WriteString("digraph cluster_topology {\n")
+	for _, clusterName := range clusterNames {
+		info := topology.allClusters[clusterName]
+		attributes := []string{"shape=circle"}
+		if clusterName == topology.primaryClusterName {
+			attributes[0] = "shape=doublecircle"
+		}
+		var styles []string
+		if clusterName == m.currentClusterName {
+			styles = append(styles, "bold")
+		}
+		if !info.Enabled {
+			styles = append(styles, "dashed")
+		}
+		if len(styles) > 0 {
+			attributes = append(attributes, "style="+strconv.Quote(strings.Join(styles, ",")))
+		}
+		fmt.Fprintf(&builder, "\t%v [%v];\n", strconv.Quote(clusterName), strings.Join(attributes, ", "))
+	}
+	for _, from := range clusterNames {
+		fromInfo := topology.allClusters[from]
+		if !fromInfo.Enabled {
+			continue
+		}
+		for _, to := range clusterNames {
+			if from != to && topology.allClusters[to].Enabled && replicatesTo(fromInfo, to) {
+				fmt.Fprintf(&builder, "\t%v -> %v;\n", strconv.Quote(from), strconv.Quote(to))
+			}
+		}
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
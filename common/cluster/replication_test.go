@@ -0,0 +1,295 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataReplicationDirection(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"all":      {Enabled: true, InitialFailoverVersion: 0},
+		"source":   {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"sink"}},
+		"sink":     {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"all"}},
+		"isolated": {Enabled: true, InitialFailoverVersion: 3, ReplicaClusters: []string{"all"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "all", "all", clusterGroup)
+
+	tests := []struct {
+		from, to string
+		dir      ReplicationDir
+	}{
+		{from: "all", to: "sink", dir: ReplicationDirBoth},
+		{from: "all", to: "source", dir: ReplicationDirForward},
+		{from: "source", to: "sink", dir: ReplicationDirForward},
+		{from: "sink", to: "source", dir: ReplicationDirBackward},
+		{from: "sink", to: "isolated", dir: ReplicationDirNone},
+		{from: "all", to: "all", dir: ReplicationDirNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			dir, err := metadata.ReplicationDirection(tt.from, tt.to)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.dir, dir)
+		})
+	}
+
+	defaultMetadata := GetTestClusterMetadata(true)
+	dir, err := defaultMetadata.ReplicationDirection(TestCurrentClusterName, TestAlternativeClusterName)
+	assert.NoError(t, err)
+	assert.Equal(t, ReplicationDirBoth, dir)
+
+	_, err = metadata.ReplicationDirection("unknown", "source")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+	_, err = metadata.ReplicationDirection("unknown", "other")
+	assert.EqualError(t, err, "unknown cluster name: unknown, other")
+}
+
+func TestMetadataOrphanedClusters(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"source":   {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"sink"}},
+		"sink":     {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"disabled"}},
+		"orphan":   {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"disabled", "orphan"}},
+		"disabled": {Enabled: false, InitialFailoverVersion: 3},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "source", "source", clusterGroup)
+	assert.Equal(t, []string{"orphan"}, metadata.OrphanedClusters())
+
+	assert.Empty(t, GetTestClusterMetadata(true).OrphanedClusters())
+
+	singleCluster := map[string]config.ClusterInformation{
+		"single": {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"single"}},
+	}
+	metadata = NewMetadata(TestFailoverVersionIncrement, "single", "single", singleCluster)
+	assert.Empty(t, metadata.OrphanedClusters())
+}
+
+func TestMetadataIsPassiveCluster(t *testing.T) {
+	active := GetTestClusterMetadata(true)
+	assert.False(t, active.IsPassiveCluster())
+	assert.Equal(t, TestCurrentClusterName, active.ActiveClusterName())
+
+	passive := NewMetadata(TestFailoverVersionIncrement, TestAlternativeClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.True(t, passive.IsPassiveCluster())
+	assert.Equal(t, TestAlternativeClusterName, passive.ActiveClusterName())
+
+	clusterGroup := map[string]config.ClusterInformation{
+		"primary": {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"other"}},
+		"current": {Enabled: true, InitialFailoverVersion: 1},
+		"other":   {Enabled: true, InitialFailoverVersion: 2},
+	}
+	notReplicated := NewMetadata(TestFailoverVersionIncrement, "primary", "current", clusterGroup)
+	assert.False(t, notReplicated.IsPassiveCluster())
+	assert.Equal(t, "primary", notReplicated.ActiveClusterName())
+}
+
+func TestMetadataToDOT(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"primary":  {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"current"}},
+		"current":  {Enabled: true, InitialFailoverVersion: 1},
+		"disabled": {Enabled: false, InitialFailoverVersion: 2},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "primary", "current", clusterGroup)
+
+	assert.Equal(t, `digraph cluster_topology {
+	"current" [shape=circle, style="bold"];
+	"disabled" [shape=circle, style="dashed"];
+	"primary" [shape=doublecircle];
+	"current" -> "primary";
+	"primary" -> "current";
+}
+`, metadata.ToDOT())
+}
+
+func TestMetadataRemoteClustersByPriority(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":    {Enabled: true, InitialFailoverVersion: 0, ReplicationPriority: 1},
+		"low":      {Enabled: true, InitialFailoverVersion: 1, ReplicationPriority: 5},
+		"high-b":   {Enabled: true, InitialFailoverVersion: 2, ReplicationPriority: 1},
+		"high-a":   {Enabled: true, InitialFailoverVersion: 3, ReplicationPriority: 1},
+		"unset-b":  {Enabled: true, InitialFailoverVersion: 4},
+		"unset-a":  {Enabled: true, InitialFailoverVersion: 5},
+		"disabled": {Enabled: false, InitialFailoverVersion: 6, ReplicationPriority: 1},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "local", "local", clusterGroup)
+	assert.Equal(t, []string{"high-a", "high-b", "low", "unset-a", "unset-b"}, metadata.RemoteClustersByPriority())
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.Equal(t, []string{TestAlternativeClusterName}, metadata.RemoteClustersByPriority())
+}
+
+func TestMetadataRemoteClustersByLag(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local":    {Enabled: true, InitialFailoverVersion: 0},
+		"behind":   {Enabled: true, InitialFailoverVersion: 1},
+		"lagging":  {Enabled: true, InitialFailoverVersion: 2, ReplicationPriority: 2},
+		"tied":     {Enabled: true, InitialFailoverVersion: 3, ReplicationPriority: 1},
+		"caughtup": {Enabled: true, InitialFailoverVersion: 4},
+		"disabled": {Enabled: false, InitialFailoverVersion: 5},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "local", "local", clusterGroup)
+
+	lag := map[string]time.Duration{
+		"behind":   time.Hour,
+		"lagging":  time.Minute,
+		"tied":     time.Minute,
+		"disabled": 2 * time.Hour,
+		"local":    3 * time.Hour,
+		"unknown":  4 * time.Hour,
+	}
+	assert.Equal(t, []string{"behind", "tied", "lagging", "caughtup"}, metadata.RemoteClustersByLag(lag))
+	assert.Equal(t, metadata.RemoteClustersByPriority(), metadata.RemoteClustersByLag(nil))
+	assert.Equal(t, metadata.RemoteClustersByPriority(), metadata.RemoteClustersByLag(map[string]time.Duration{"unknown": time.Hour}))
+}
+
+func TestMetadataDependentsOfResidue(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"all":      {Enabled: true, InitialFailoverVersion: 0},
+		"source":   {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"sink"}},
+		"sink":     {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"all"}},
+		"isolated": {Enabled: true, InitialFailoverVersion: 3, ReplicaClusters: []string{"all"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "all", "all", clusterGroup)
+
+	clusterName, ok := metadata.ClusterForResidue(1)
+	assert.True(t, ok)
+	assert.Equal(t, "source", clusterName)
+	assert.Equal(t, []string{"all", "sink"}, metadata.DependentsOfResidue(1))
+	assert.Equal(t, []string{"all"}, metadata.DependentsOfResidue(3))
+
+	// unowned residues
+	for _, residue := range []int64{5, -1, TestFailoverVersionIncrement + 1} {
+		_, ok = metadata.ClusterForResidue(residue)
+		assert.False(t, ok)
+		assert.Empty(t, metadata.DependentsOfResidue(residue))
+	}
+}
+
+func TestMetadataGetClusterMaxReplicationLag(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"configured": {Enabled: true, InitialFailoverVersion: 0, MaxReplicationLag: time.Minute},
+		"default":    {Enabled: true, InitialFailoverVersion: 1},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "configured", "configured", clusterGroup)
+
+	lag, err := metadata.GetClusterMaxReplicationLag("configured")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, lag)
+
+	lag, err = metadata.GetClusterMaxReplicationLag("default")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxReplicationLag, lag)
+
+	_, err = metadata.GetClusterMaxReplicationLag("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+}
+
+func TestMetadataReplicationCycles(t *testing.T) {
+	tests := []struct {
+		msg          string
+		clusterGroup map[string]config.ClusterInformation
+		cycles       [][]string
+	}{
+		{
+			msg: "cycle back to the root",
+			clusterGroup: map[string]config.ClusterInformation{
+				"root":   {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"left", "right"}},
+				"left":   {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"leaf"}},
+				"right":  {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"leaf"}},
+				"leaf":   {Enabled: true, InitialFailoverVersion: 3, ReplicaClusters: []string{"root"}},
+				"remote": {Enabled: false, InitialFailoverVersion: 4},
+			},
+			cycles: [][]string{{"leaf", "left", "right", "root"}},
+		},
+		{
+			msg: "acyclic",
+			clusterGroup: map[string]config.ClusterInformation{
+				"root":     {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"left", "right"}},
+				"left":     {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"disabled"}},
+				"right":    {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"disabled"}},
+				"disabled": {Enabled: false, InitialFailoverVersion: 3},
+			},
+		},
+		{
+			msg: "separate cycles",
+			clusterGroup: map[string]config.ClusterInformation{
+				"a": {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"b"}},
+				"b": {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"a"}},
+				"c": {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"d", "a"}},
+				"d": {Enabled: true, InitialFailoverVersion: 3, ReplicaClusters: []string{"e"}},
+				"e": {Enabled: true, InitialFailoverVersion: 4, ReplicaClusters: []string{"c"}},
+				"f": {Enabled: true, InitialFailoverVersion: 5, ReplicaClusters: []string{"f", "a"}},
+			},
+			cycles: [][]string{{"a", "b"}, {"c", "d", "e"}},
+		},
+		{
+			msg:          "default replication to all",
+			clusterGroup: TestAllClusterInfo,
+			cycles:       [][]string{{TestCurrentClusterName, TestAlternativeClusterName}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			firstCluster := sortedClusterNames(tt.clusterGroup)[0]
+			metadata := NewMetadata(TestFailoverVersionIncrement, firstCluster, firstCluster, tt.clusterGroup)
+			assert.Equal(t, tt.cycles, metadata.ReplicationCycles())
+			assert.Equal(t, len(tt.cycles) == 0, metadata.IsReplicationAcyclic())
+		})
+	}
+}
+
+func TestMetadataReplicationPlanForVersion(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"source":   {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"east", "west", "disabled"}, AllowDisabledReplicaClusters: true},
+		"east":     {Enabled: true, InitialFailoverVersion: 1},
+		"west":     {Enabled: true, InitialFailoverVersion: 5},
+		"other":    {Enabled: true, InitialFailoverVersion: 3},
+		"disabled": {Enabled: false, InitialFailoverVersion: 4},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "source", "source", clusterGroup)
+
+	plan, err := metadata.ReplicationPlanForVersion(32)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReplicationTarget{
+		{Cluster: "east", ExpectedVersion: 31},
+		{Cluster: "west", ExpectedVersion: 35},
+	}, plan)
+	for _, target := range plan {
+		cluster, generation, err := metadata.ResolveFailoverVersion(target.ExpectedVersion)
+		assert.NoError(t, err)
+		assert.Equal(t, target.Cluster, cluster)
+		assert.Equal(t, int64(3), generation)
+	}
+
+	_, err = metadata.ReplicationPlanForVersion(31)
+	assert.EqualError(t, err, "failover version 31 does not belong to the current cluster source")
+
+	// single cluster deployments have no destination
+	plan, err = NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestSingleDCClusterInfo).
+		ReplicationPlanForVersion(10)
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// ReservedTestCluster is the cluster name failover versions with a reserved test residue resolve to
+const ReservedTestCluster = "reserved-test-cluster"
+
+// WithReservedTestResidues returns an Option reserving the given initial failover versions (residues) for
+// test generated events, so that their failover versions never collide with the ones of real clusters
+func WithReservedTestResidues(residues ...int64) Option {
+	return func(m *Metadata) {
+		m.reservedTestResidues = make(map[int64]struct{}, len(residues))
+		for _, residue := range residues {
+			m.reservedTestResidues[residue] = struct{}{}
+		}
+	}
+}
+
+// IsReservedTestVersion return whether the failover version has a reserved test residue
+func (m Metadata) IsReservedTestVersion(version int64) bool {
+	if version < 0 || len(m.reservedTestResidues) == 0 {
+		return false
+	}
+	_, ok := m.reservedTestResidues[version%m.failoverVersionIncrement]
+	return ok
+}
+
+func (m Metadata) validateReservedTestResidues(clusterGroup map[string]config.ClusterInformation) error {
+	var errs error
+	for residue := range m.reservedTestResidues {
+		if residue < 0 || residue >= m.failoverVersionIncrement {
+			errs = multierr.Append(errs, fmt.Errorf(
+				"reserved test residue %v is not within [0, %v)",
+				residue,
+				m.failoverVersionIncrement,
+			))
+		}
+	}
+	for _, clusterName := range sortedClusterNames(clusterGroup) {
+		initialFailoverVersion := clusterGroup[clusterName].InitialFailoverVersion
+		if _, ok := m.reservedTestResidues[initialFailoverVersion]; ok {
+			errs = multierr.Append(errs, fmt.Errorf(
+				"cluster %v: initial version %v is reserved for tests",
+				clusterName,
+				initialFailoverVersion,
+			))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common"
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataIsReservedTestVersion(t *testing.T) {
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithReservedTestResidues(8, 9),
+	)
+
+	assert.True(t, metadata.IsReservedTestVersion(9))
+	assert.True(t, metadata.IsReservedTestVersion(18))
+	assert.True(t, metadata.IsReservedTestVersion(109))
+	assert.False(t, metadata.IsReservedTestVersion(11))
+	assert.False(t, metadata.IsReservedTestVersion(common.EmptyVersion))
+	assert.Equal(t, ReservedTestCluster, metadata.ClusterNameForFailoverVersion(19))
+	assert.Equal(t, TestAlternativeClusterName, metadata.ClusterNameForFailoverVersion(11))
+
+	assert.False(t, GetTestClusterMetadata(true).IsReservedTestVersion(9))
+}
+
+func TestMetadataReservedTestResiduesRejectedForRealClusters(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}
+	clusterGroupMetadata := &config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup:             clusterGroup,
+	}
+
+	_, err := NewValidatedMetadata(clusterGroupMetadata, WithReservedTestResidues(9))
+	require.NoError(t, err)
+
+	_, err = NewValidatedMetadata(clusterGroupMetadata, WithReservedTestResidues(TestAlternativeClusterInitialFailoverVersion, 10))
+	assert.EqualError(t, err, "reserved test residue 10 is not within [0, 10); cluster standby: initial version 1 is reserved for tests")
+
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		clusterGroup,
+		WithReservedTestResidues(TestDisabledClusterInitialFailoverVersion),
+	)
+	err = metadata.UpdateClusterInformation(TestCurrentClusterName, TestAllClusterInfo)
+	assert.EqualError(t, err, "cluster disabled: initial version 2 is reserved for tests")
+	assert.Equal(t, clusterGroup, metadata.GetAllClusterInfo())
+}
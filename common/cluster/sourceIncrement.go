@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// TranslateFailoverVersion translates a failover version generated with one failover version increment
+// to the failover version with the same generation and residue for another failover version increment
+func TranslateFailoverVersion(failoverVersion int64, fromIncrement int64, toIncrement int64) (int64, error) {
+	if fromIncrement <= 0 || toIncrement <= 0 {
+		return 0, fmt.Errorf("failover version increments %v and %v must be positive", fromIncrement, toIncrement)
+	}
+	residue := failoverVersion % fromIncrement
+	if residue < 0 || residue >= toIncrement {
+		return 0, fmt.Errorf("failover version %v: residue %v is not within [0, %v)", failoverVersion, residue, toIncrement)
+	}
+	return failoverVersion/fromIncrement*toIncrement + residue, nil
+}
+
+// HasHeterogeneousIncrements return whether the versions of any cluster were generated with a source increment
+// different from the failover version increment
+func (m Metadata) HasHeterogeneousIncrements() bool {
+	topology := m.getTopology()
+	for _, clusterName := range topology.sourceIncrementClusters {
+		if topology.allClusters[clusterName].SourceIncrement != m.failoverVersionIncrement {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterNameForSourceIncrementVersion resolves the failover version to the first cluster, by name, whose
+// source increment differs from the failover version increment and which owns the version once translated.
+// It is only consulted if no cluster owns the residue of the version, config.ValidateClusterGroup rejects
+// cluster groups where a residue could be owned by both.
+func (m Metadata) clusterNameForSourceIncrementVersion(topology *clusterTopology, failoverVersion int64) (string, bool) {
+	for _, clusterName := range topology.sourceIncrementClusters {
+		info := topology.allClusters[clusterName]
+		if info.SourceIncrement == m.failoverVersionIncrement {
+			continue
+		}
+		translated, err := TranslateFailoverVersion(failoverVersion, info.SourceIncrement, m.failoverVersionIncrement)
+		if err == nil && translated%m.failoverVersionIncrement == info.InitialFailoverVersion {
+			return clusterName, true
+		}
+	}
+	return "", false
+}
+
+// sourceIncrementClusters returns the sorted names of the clusters with a source increment, only those
+// are sorted as the cluster group can be large
+func sourceIncrementClusters(clusterGroup map[string]config.ClusterInformation) []string {
+	var clusterNames []string
+	for clusterName, info := range clusterGroup {
+		if info.SourceIncrement > 0 {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	sort.Strings(clusterNames)
+	return clusterNames
+}
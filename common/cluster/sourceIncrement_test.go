@@ -0,0 +1,116 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestTranslateFailoverVersion(t *testing.T) {
+	version, err := TranslateFailoverVersion(23, 10, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(203), version)
+
+	version, err = TranslateFailoverVersion(203, 100, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(23), version)
+
+	_, err = TranslateFailoverVersion(15, 100, 10)
+	assert.EqualError(t, err, "failover version 15: residue 15 is not within [0, 10)")
+	_, err = TranslateFailoverVersion(15, 0, 10)
+	assert.EqualError(t, err, "failover version increments 0 and 10 must be positive")
+}
+
+func TestMetadataSourceIncrement(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"local": {
+			Enabled:                true,
+			InitialFailoverVersion: 0,
+			RPCName:                "cadence-frontend",
+			RPCAddress:             "127.0.0.1:7933",
+			RPCTransport:           "tchannel",
+		},
+		"migrated": {
+			Enabled:                true,
+			InitialFailoverVersion: 3,
+			SourceIncrement:        10,
+			RPCName:                "cadence-frontend",
+			RPCAddress:             "127.0.0.1:8933",
+			RPCTransport:           "tchannel",
+		},
+	}
+	metadata := NewMetadata(100, "local", "local", clusterGroup)
+	assert.True(t, metadata.HasHeterogeneousIncrements())
+
+	// generation 2 of the source increment is translated to version 203
+	assert.Equal(t, "migrated", metadata.ClusterNameForFailoverVersion(23))
+	assert.Equal(t, "migrated", metadata.ClusterNameForFailoverVersion(203))
+	assert.Equal(t, "local", metadata.ClusterNameForFailoverVersion(100))
+
+	// a source increment matching the failover version increment is the regular resolution
+	migrated := clusterGroup["migrated"]
+	migrated.SourceIncrement = 100
+	clusterGroup["migrated"] = migrated
+	metadata = NewMetadata(100, "local", "local", clusterGroup)
+	assert.False(t, metadata.HasHeterogeneousIncrements())
+	assert.Equal(t, "migrated", metadata.ClusterNameForFailoverVersion(203))
+
+	metadata = NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.False(t, metadata.HasHeterogeneousIncrements())
+}
+
+func TestMetadataSourceIncrementNativeResiduePrecedence(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"native": {
+			Enabled:                true,
+			InitialFailoverVersion: 13,
+			RPCName:                "cadence-frontend",
+			RPCAddress:             "127.0.0.1:7933",
+		},
+		"migrated": {
+			Enabled:                true,
+			InitialFailoverVersion: 3,
+			SourceIncrement:        10,
+			RPCName:                "cadence-frontend",
+			RPCAddress:             "127.0.0.1:8933",
+		},
+	}
+
+	// versions owned natively are not translated
+	metadata := NewMetadata(100, "native", "native", clusterGroup)
+	assert.Equal(t, "native", metadata.ClusterNameForFailoverVersion(13))
+	assert.Equal(t, "native", metadata.ClusterNameForFailoverVersion(113))
+	assert.Equal(t, "migrated", metadata.ClusterNameForFailoverVersion(23))
+
+	// as the versions are ambiguous, the cluster group is rejected
+	_, err := NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: 100,
+		PrimaryClusterName:       "native",
+		CurrentClusterName:       "native",
+		ClusterGroup:             clusterGroup,
+	})
+	assert.EqualError(t, err, "cluster native: failover versions with initial version 13 overlap the versions of cluster migrated with source increment 10")
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+type (
+	// MetadataState is a snapshot of the mutable state of a Metadata, see SaveState
+	MetadataState struct {
+		topology          *clusterTopology
+		openCircuits      map[string]struct{}
+		replicationPaused bool
+		remoteHandshakes  map[string]RemoteHandshake
+	}
+)
+
+// SaveState return a snapshot of the mutable state of the Metadata, i.e. the topology, the open circuits,
+// whether replication is paused and the recorded remote handshakes, e.g. to restore it between test cases
+func (m Metadata) SaveState() MetadataState {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+
+	state := MetadataState{
+		topology:          m.topology.current,
+		replicationPaused: m.topology.replicationPaused,
+	}
+	if len(m.topology.openCircuits) > 0 {
+		state.openCircuits = make(map[string]struct{}, len(m.topology.openCircuits))
+		for clusterName := range m.topology.openCircuits {
+			state.openCircuits[clusterName] = struct{}{}
+		}
+	}
+	if len(m.topology.remoteHandshakes) > 0 {
+		state.remoteHandshakes = make(map[string]RemoteHandshake, len(m.topology.remoteHandshakes))
+		for clusterName, handshake := range m.topology.remoteHandshakes {
+			state.remoteHandshakes[clusterName] = handshake
+		}
+	}
+	return state
+}
+
+// RestoreState reinstates the state saved by SaveState atomically, the change is visible to all copies
+// of the Metadata. Topology changes are reported to the topology change sink, if any, and bump the config version,
+// which is never restored so that consumers caching data derived from the Metadata recompute it.
+func (m Metadata) RestoreState(state MetadataState) {
+	if state.topology == nil {
+		return
+	}
+
+	m.topology.Lock()
+	events, addressChanges := m.replaceTopologyLocked(state.topology)
+	m.topology.openCircuits = nil
+	for clusterName := range state.openCircuits {
+		if m.topology.openCircuits == nil {
+			m.topology.openCircuits = make(map[string]struct{}, len(state.openCircuits))
+		}
+		m.topology.openCircuits[clusterName] = struct{}{}
+	}
+	m.topology.replicationPaused = state.replicationPaused
+	m.topology.remoteHandshakes = nil
+	for clusterName, handshake := range state.remoteHandshakes {
+		if m.topology.remoteHandshakes == nil {
+			m.topology.remoteHandshakes = make(map[string]RemoteHandshake, len(state.remoteHandshakes))
+		}
+		m.topology.remoteHandshakes[clusterName] = handshake
+	}
+	m.topology.Unlock()
+
+	m.notifyRPCAddressChanges(addressChanges)
+	m.recordChanges(events)
+}
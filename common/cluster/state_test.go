@@ -0,0 +1,57 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataSaveRestoreState(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	original := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	state := metadata.SaveState()
+
+	require.NoError(t, metadata.SetClusterEnabled(TestAlternativeClusterName, false))
+	metadata.SetClusterCircuitOpen(TestAlternativeClusterName, true)
+	metadata.SetReplicationPaused(true)
+	assert.False(t, metadata.Equal(original))
+	configVersion := metadata.ConfigVersion()
+
+	metadata.RestoreState(state)
+	assert.True(t, metadata.Equal(original))
+	assert.False(t, metadata.IsClusterCircuitOpen(TestAlternativeClusterName))
+	assert.True(t, metadata.IsReplicationEnabled())
+	assert.Greater(t, metadata.ConfigVersion(), configVersion)
+
+	// the saved state is not affected by later changes
+	metadata.SetClusterCircuitOpen(TestAlternativeClusterName, true)
+	require.NoError(t, metadata.PromotePrimary(TestAlternativeClusterName))
+	saved := metadata.SaveState()
+	metadata.SetClusterCircuitOpen(TestAlternativeClusterName, false)
+	metadata.RestoreState(state)
+	assert.True(t, metadata.Equal(original))
+	metadata.RestoreState(saved)
+	assert.True(t, metadata.IsClusterCircuitOpen(TestAlternativeClusterName))
+	assert.False(t, metadata.IsPrimaryCluster())
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// GetClusterTLSConfig return the TLS config built from the TLS settings of the cluster, nil if TLS is disabled.
+// It is cached per cluster until the cluster group is updated, so the returned config must not be modified.
+func (m Metadata) GetClusterTLSConfig(clusterName string) (*tls.Config, error) {
+	topology := m.getTopology()
+	if tlsConfig, ok := topology.tlsConfigs.Load(clusterName); ok {
+		return tlsConfig.(*tls.Config), nil
+	}
+
+	info, ok := topology.allClusters[clusterName]
+	if !ok {
+		return nil, fmt.Errorf("unknown cluster name: %v", clusterName)
+	}
+	tlsConfig, err := info.TLS.ToTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cluster %v: invalid tls config: %w", clusterName, err)
+	}
+	actual, _ := topology.tlsConfigs.LoadOrStore(clusterName, tlsConfig)
+	return actual.(*tls.Config), nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestMetadataGetClusterTLSConfig(t *testing.T) {
+	invalidCertFile := filepath.Join(t.TempDir(), "invalid.crt")
+	require.NoError(t, ioutil.WriteFile(invalidCertFile, []byte("not a certificate"), 0600))
+
+	clusterGroup := map[string]config.ClusterInformation{
+		"plain": {Enabled: true, InitialFailoverVersion: 0, RPCName: "cadence-frontend", RPCAddress: "127.0.0.1:7933"},
+		"mtls": {Enabled: true, InitialFailoverVersion: 1, RPCName: "cadence-frontend", RPCAddress: "127.0.0.1:8933", TLS: config.TLS{
+			Enabled:    true,
+			CertFile:   "../../config/credentials/client.crt",
+			KeyFile:    "../../config/credentials/client.key",
+			CaFile:     "../../config/credentials/client.crt",
+			ServerName: "mtls.example.com",
+		}},
+		"invalid": {Enabled: true, InitialFailoverVersion: 2, RPCName: "cadence-frontend", RPCAddress: "127.0.0.1:9933", TLS: config.TLS{
+			Enabled:  true,
+			CertFile: invalidCertFile,
+			KeyFile:  "../../config/credentials/client.key",
+		}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "plain", "plain", clusterGroup)
+
+	tlsConfig, err := metadata.GetClusterTLSConfig("plain")
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = metadata.GetClusterTLSConfig("mtls")
+	require.NoError(t, err)
+	assert.Equal(t, "mtls.example.com", tlsConfig.ServerName)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	cached, err := metadata.GetClusterTLSConfig("mtls")
+	assert.NoError(t, err)
+	assert.Same(t, tlsConfig, cached)
+
+	_, err = metadata.GetClusterTLSConfig("invalid")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster invalid: invalid tls config")
+
+	_, err = metadata.GetClusterTLSConfig("unknown")
+	assert.EqualError(t, err, "unknown cluster name: unknown")
+
+	// the cache is dropped on update
+	require.NoError(t, metadata.UpdateClusterInformation("plain", clusterGroup))
+	updated, err := metadata.GetClusterTLSConfig("mtls")
+	assert.NoError(t, err)
+	assert.NotSame(t, tlsConfig, updated)
+}
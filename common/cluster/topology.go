@@ -0,0 +1,521 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/clock"
+	"github.com/uber/cadence/common/config"
+)
+
+const (
+	// TopologyChangeTypeClusterAdded indicates a cluster was added to the cluster group
+	TopologyChangeTypeClusterAdded TopologyChangeType = iota
+	// TopologyChangeTypeClusterRemoved indicates a cluster was removed from the cluster group
+	TopologyChangeTypeClusterRemoved
+	// TopologyChangeTypeClusterEnabled indicates a cluster was enabled
+	TopologyChangeTypeClusterEnabled
+	// TopologyChangeTypeClusterDisabled indicates a cluster was disabled
+	TopologyChangeTypeClusterDisabled
+	// TopologyChangeTypeClusterUpdated indicates other information of a cluster was changed
+	TopologyChangeTypeClusterUpdated
+	// TopologyChangeTypePrimaryClusterChanged indicates the primary cluster was changed
+	TopologyChangeTypePrimaryClusterChanged
+)
+
+type (
+	// TopologyChangeType is the type of a topology change
+	TopologyChangeType int
+
+	// TopologyChangeEvent describes a single topology change
+	TopologyChangeEvent struct {
+		// Timestamp is the time when the change was applied
+		Timestamp time.Time
+		// Type is the type of the change
+		Type TopologyChangeType
+		// ClusterName is the name of the changed cluster, for primary cluster changes it is the new primary cluster
+		ClusterName string
+		// OldValue and NewValue are the values before and after the change:
+		// config.ClusterInformation for cluster added / removed / updated (nil if absent),
+		// bool for cluster enabled / disabled and string for primary cluster changed.
+		OldValue interface{}
+		NewValue interface{}
+	}
+
+	// TopologyChangeSink records topology changes, e.g. to keep an audit trail
+	TopologyChangeSink interface {
+		// RecordChange is invoked for each change once the update is applied
+		RecordChange(event TopologyChangeEvent)
+	}
+
+	// sharedTopology holds the current topology, it is shared by all copies of a Metadata
+	sharedTopology struct {
+		sync.RWMutex
+		current *clusterTopology
+		// configVersion is increased by every update changing the topology
+		configVersion uint64
+		// openCircuits contains the clusters with an open circuit, which are skipped by remote cluster selection
+		openCircuits map[string]struct{}
+		// replicationPaused skips all remote clusters in remote cluster selection
+		replicationPaused bool
+		// remoteHandshakes contains the last handshake recorded for each remote cluster
+		remoteHandshakes map[string]RemoteHandshake
+		// pendingChangesBase is the topology before the first update of the pending debounce window, nil if none
+		pendingChangesBase *clusterTopology
+		// debounceTimer reports the pending changes once the debounce window settles
+		debounceTimer clock.Timer
+		// closed disables the debounce window, see Close
+		closed bool
+	}
+
+	// clusterTopology is an immutable view over the clusters, it is replaced as a whole on update
+	clusterTopology struct {
+		// primaryClusterName is the name of the primary cluster, only the primary cluster can register / update domain
+		// all clusters can do domain failover
+		primaryClusterName string
+		// currentClusterName is the name of the current cluster
+		currentClusterName string
+		// allClusters contains all cluster info
+		allClusters map[string]config.ClusterInformation
+		// versionToClusterName contains all initial version -> corresponding cluster name
+		versionToClusterName map[int64]string
+		// sourceIncrementClusters contains the sorted names of clusters with a source increment
+		sourceIncrementClusters []string
+
+		// derivedOnce guards enabledClusters and remoteClusters, which are computed on first access
+		derivedOnce sync.Once
+		// enabledClusters contains enabled info
+		enabledClusters map[string]config.ClusterInformation
+		// remoteClusters contains enabled and remote info
+		remoteClusters map[string]config.ClusterInformation
+
+		// tlsConfigs caches the TLS config of each cluster, see GetClusterTLSConfig
+		tlsConfigs sync.Map
+	}
+)
+
+func (t TopologyChangeType) String() string {
+	switch t {
+	case TopologyChangeTypeClusterAdded:
+		return "ClusterAdded"
+	case TopologyChangeTypeClusterRemoved:
+		return "ClusterRemoved"
+	case TopologyChangeTypeClusterEnabled:
+		return "ClusterEnabled"
+	case TopologyChangeTypeClusterDisabled:
+		return "ClusterDisabled"
+	case TopologyChangeTypeClusterUpdated:
+		return "ClusterUpdated"
+	case TopologyChangeTypePrimaryClusterChanged:
+		return "PrimaryClusterChanged"
+	default:
+		return fmt.Sprintf("TopologyChangeType(%d)", int(t))
+	}
+}
+
+// UpdateClusterInformation replaces the primary cluster and the cluster group, the change is visible to
+// all copies of the Metadata. The cluster information transform, if any, is applied to the cluster group
+// and the new topology must pass the registered validators.
+// Changes are reported to the topology change sink, if any, and bump the config version.
+// With a topology change debounce window, the changes are reported once no update happened for the window,
+// as the net diff between the topology before the first update and the final topology.
+func (m Metadata) UpdateClusterInformation(
+	primaryClusterName string,
+	clusterGroup map[string]config.ClusterInformation,
+) error {
+	newClusterGroup, err := m.transformClusterGroup(clusterGroup)
+	if err != nil {
+		return err
+	}
+	return m.replaceClusterGroup(primaryClusterName, newClusterGroup)
+}
+
+// replaceClusterGroup is UpdateClusterInformation for an already transformed cluster group
+func (m Metadata) replaceClusterGroup(
+	primaryClusterName string,
+	newClusterGroup map[string]config.ClusterInformation,
+) error {
+	if err := m.validateTopology(primaryClusterName, newClusterGroup); err != nil {
+		return err
+	}
+
+	newTopology := newClusterTopology(primaryClusterName, m.currentClusterName, newClusterGroup)
+	candidate := m
+	candidate.topology = &sharedTopology{current: newTopology}
+	if err := runMetadataValidators(candidate); err != nil {
+		return err
+	}
+
+	m.topology.Lock()
+	oldTopology := m.topology.current
+	events, addressChanges := m.replaceTopologyLocked(newTopology)
+	if m.topologyChangeDebounce > 0 && !m.topology.closed {
+		if len(events) > 0 {
+			m.topology.pendingChangesBase = oldTopology
+		}
+		if m.topology.pendingChangesBase != nil {
+			m.resetDebounceTimerLocked()
+			events = nil
+		}
+	}
+	m.topology.Unlock()
+
+	m.notifyRPCAddressChanges(addressChanges)
+	m.recordChanges(events)
+	return nil
+}
+
+// SetClusterEnabled enables or disables a single cluster, see ApplyClusterPatch.
+// Like in the config validation, a cluster can only be enabled with an RPC name and address.
+func (m Metadata) SetClusterEnabled(clusterName string, enabled bool) error {
+	return m.ApplyClusterPatch(ClusterPatch{ClusterName: clusterName, Enabled: &enabled})
+}
+
+// PromotePrimary makes the given enabled, non archival-only cluster the primary cluster, the change is visible
+// to all copies of the Metadata. The new topology must pass the registered validators.
+// The change is reported to the topology change sink, if any, promoting the current primary cluster is a no-op.
+func (m Metadata) PromotePrimary(clusterName string) error {
+	m.topology.Lock()
+	oldTopology := m.topology.current
+	info, ok := oldTopology.allClusters[clusterName]
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("cluster %v is not specified in the cluster group", clusterName)
+	case !info.Enabled:
+		err = fmt.Errorf("cluster %v is not enabled", clusterName)
+	case info.ArchivalOnly:
+		err = fmt.Errorf("cluster %v is archival only", clusterName)
+	}
+	if err != nil || clusterName == oldTopology.primaryClusterName {
+		m.topology.Unlock()
+		return err
+	}
+
+	newTopology := &clusterTopology{
+		primaryClusterName:      clusterName,
+		currentClusterName:      oldTopology.currentClusterName,
+		allClusters:             oldTopology.allClusters,
+		versionToClusterName:    oldTopology.versionToClusterName,
+		sourceIncrementClusters: oldTopology.sourceIncrementClusters,
+	}
+	candidate := m
+	candidate.topology = &sharedTopology{current: newTopology}
+	if err := runMetadataValidators(candidate); err != nil {
+		m.topology.Unlock()
+		return err
+	}
+	events, addressChanges := m.replaceTopologyLocked(newTopology)
+	m.topology.Unlock()
+
+	m.notifyRPCAddressChanges(addressChanges)
+	m.recordChanges(events)
+	return nil
+}
+
+// CanRemoveCluster return whether the cluster can be safely removed from the cluster group, i.e. it is neither
+// the primary nor the current cluster, no other cluster lists it as a replica cluster and no other enabled cluster
+// replicates to it implicitly, as clusters without replica clusters replicate to all clusters.
+// If not, the reasons are returned as well.
+func (m Metadata) CanRemoveCluster(clusterName string) (bool, []string) {
+	topology := m.getTopology()
+	if _, ok := topology.allClusters[clusterName]; !ok {
+		return false, []string{fmt.Sprintf("cluster %v is not specified in the cluster group", clusterName)}
+	}
+
+	var reasons []string
+	if clusterName == topology.primaryClusterName {
+		reasons = append(reasons, fmt.Sprintf("cluster %v is the primary cluster", clusterName))
+	}
+	if clusterName == m.currentClusterName {
+		reasons = append(reasons, fmt.Sprintf("cluster %v is the current cluster", clusterName))
+	}
+	for _, otherClusterName := range sortedClusterNames(topology.allClusters) {
+		if otherClusterName == clusterName {
+			continue
+		}
+		otherInfo := topology.allClusters[otherClusterName]
+		switch {
+		case len(otherInfo.ReplicaClusters) == 0 && otherInfo.Enabled:
+			reasons = append(reasons, fmt.Sprintf(
+				"cluster %v is an implicit replica cluster of %v, which replicates to all clusters",
+				clusterName,
+				otherClusterName,
+			))
+		case len(otherInfo.ReplicaClusters) > 0 && replicatesTo(otherInfo, clusterName):
+			reasons = append(reasons, fmt.Sprintf("cluster %v is a replica cluster of %v", clusterName, otherClusterName))
+		}
+	}
+	return len(reasons) == 0, reasons
+}
+
+// ConfigVersion return the version of the topology, it is increased by every update changing the topology,
+// so that consumers caching data derived from the Metadata know when to recompute it
+func (m Metadata) ConfigVersion() uint64 {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+	return m.topology.configVersion
+}
+
+// replaceTopologyLocked replaces the current topology and returns the changes, bumping the config version if any.
+// The changed RPC addresses are returned as well, they are not debounced. The topology lock must be held.
+func (m Metadata) replaceTopologyLocked(newTopology *clusterTopology) ([]TopologyChangeEvent, []rpcAddressChange) {
+	events := diffTopology(m.timeSource.Now(), m.topology.current, newTopology)
+	addressChanges := diffRPCAddresses(m.topology.current, newTopology)
+	m.topology.current = newTopology
+	if len(events) > 0 {
+		m.topology.configVersion++
+	}
+	if m.topology.pendingChangesBase != nil {
+		// the changes are reported with the pending changes once the debounce window settles
+		return nil, addressChanges
+	}
+	return events, addressChanges
+}
+
+// resetDebounceTimerLocked (re)starts the debounce window of the pending changes. The topology lock must be held.
+// The timer is created with the time source if it is a clock.TimerSource, with a real timer otherwise.
+func (m Metadata) resetDebounceTimerLocked() {
+	if m.topology.debounceTimer == nil {
+		if timerSource, ok := m.timeSource.(clock.TimerSource); ok {
+			m.topology.debounceTimer = timerSource.AfterFunc(m.topologyChangeDebounce, m.flushPendingChanges)
+		} else {
+			m.topology.debounceTimer = time.AfterFunc(m.topologyChangeDebounce, m.flushPendingChanges)
+		}
+		return
+	}
+	m.topology.debounceTimer.Reset(m.topologyChangeDebounce)
+}
+
+// Close stops the topology change debounce timer shared by all copies of the Metadata. Pending changes are
+// reported immediately and later changes are reported without debouncing. Close can be called multiple times.
+func (m Metadata) Close() {
+	m.topology.Lock()
+	m.topology.closed = true
+	if m.topology.debounceTimer != nil {
+		m.topology.debounceTimer.Stop()
+		m.topology.debounceTimer = nil
+	}
+	m.topology.Unlock()
+
+	m.flushPendingChanges()
+}
+
+// flushPendingChanges reports the net diff of the pending changes, if any
+func (m Metadata) flushPendingChanges() {
+	m.topology.Lock()
+	base := m.topology.pendingChangesBase
+	m.topology.pendingChangesBase = nil
+	current := m.topology.current
+	m.topology.Unlock()
+
+	if base == nil {
+		return
+	}
+	m.recordChanges(diffTopology(m.timeSource.Now(), base, current))
+}
+
+func (m Metadata) getTopology() *clusterTopology {
+	m.topology.RLock()
+	defer m.topology.RUnlock()
+	return m.topology.current
+}
+
+// notifyRPCAddressChanges invokes the RPC address change callback, if any, for each changed RPC address
+func (m Metadata) notifyRPCAddressChanges(addressChanges []rpcAddressChange) {
+	if m.onRPCAddressChange == nil {
+		return
+	}
+	for _, change := range addressChanges {
+		m.onRPCAddressChange(change.clusterName, change.oldAddress, change.newAddress)
+	}
+}
+
+func (m Metadata) recordChanges(events []TopologyChangeEvent) {
+	if m.topologyChangeSink == nil {
+		return
+	}
+	for _, event := range events {
+		m.topologyChangeSink.RecordChange(event)
+	}
+}
+
+// validateTopology validates the primary and current cluster and, with config.ValidateClusterGroup,
+// the cluster group of a new topology
+func (m Metadata) validateTopology(
+	primaryClusterName string,
+	clusterGroup map[string]config.ClusterInformation,
+) error {
+	var errs error
+	if info, ok := clusterGroup[primaryClusterName]; !ok {
+		errs = multierr.Append(errs, fmt.Errorf("primary cluster %v is not specified in the cluster group", primaryClusterName))
+	} else if info.ArchivalOnly {
+		errs = multierr.Append(errs, fmt.Errorf("primary cluster %v is archival only", primaryClusterName))
+	}
+	if _, ok := clusterGroup[m.currentClusterName]; !ok {
+		errs = multierr.Append(errs, fmt.Errorf("current cluster %v is not specified in the cluster group", m.currentClusterName))
+	}
+	errs = multierr.Append(errs, config.ValidateClusterGroup(m.failoverVersionIncrement, clusterGroup))
+	return multierr.Append(errs, m.validateReservedTestResidues(clusterGroup))
+}
+
+func newClusterTopology(
+	primaryClusterName string,
+	currentClusterName string,
+	clusterGroup map[string]config.ClusterInformation,
+) *clusterTopology {
+	versionToClusterName := make(map[int64]string)
+	for clusterName, info := range clusterGroup {
+		versionToClusterName[info.InitialFailoverVersion] = clusterName
+	}
+
+	return &clusterTopology{
+		primaryClusterName:      primaryClusterName,
+		currentClusterName:      currentClusterName,
+		allClusters:             clusterGroup,
+		versionToClusterName:    versionToClusterName,
+		sourceIncrementClusters: sourceIncrementClusters(clusterGroup),
+	}
+}
+
+func (t *clusterTopology) getEnabledClusters() map[string]config.ClusterInformation {
+	t.computeDerivedClusters()
+	return t.enabledClusters
+}
+
+func (t *clusterTopology) getRemoteClusters() map[string]config.ClusterInformation {
+	t.computeDerivedClusters()
+	return t.remoteClusters
+}
+
+func (t *clusterTopology) computeDerivedClusters() {
+	t.derivedOnce.Do(func() {
+		// We never use disable clusters, filter them out
+		enabledClusters := map[string]config.ClusterInformation{}
+		for cluster, info := range t.allClusters {
+			if info.Enabled {
+				enabledClusters[cluster] = info
+			}
+		}
+
+		remoteClusters := map[string]config.ClusterInformation{}
+		for cluster, info := range enabledClusters {
+			if cluster != t.currentClusterName {
+				remoteClusters[cluster] = info
+			}
+		}
+
+		t.enabledClusters = enabledClusters
+		t.remoteClusters = remoteClusters
+	})
+}
+
+// diffRPCAddresses returns the RPC address changes of the clusters in both topologies, ordered by cluster name
+func diffRPCAddresses(oldTopology *clusterTopology, newTopology *clusterTopology) []rpcAddressChange {
+	var addressChanges []rpcAddressChange
+	for _, clusterName := range sortedClusterNames(newTopology.allClusters) {
+		oldInfo, ok := oldTopology.allClusters[clusterName]
+		if newAddress := newTopology.allClusters[clusterName].RPCAddress; ok && oldInfo.RPCAddress != newAddress {
+			addressChanges = append(addressChanges, rpcAddressChange{
+				clusterName: clusterName,
+				oldAddress:  oldInfo.RPCAddress,
+				newAddress:  newAddress,
+			})
+		}
+	}
+	return addressChanges
+}
+
+// diffTopology returns the changes between two topologies, ordered by cluster name, with the primary cluster change last
+// If a cluster is enabled or disabled along with other changes, the enabled / disabled event is followed by
+// an updated event carrying the full cluster info
+func diffTopology(now time.Time, oldTopology *clusterTopology, newTopology *clusterTopology) []TopologyChangeEvent {
+	clusters := make(map[string]config.ClusterInformation, len(newTopology.allClusters))
+	for clusterName, info := range oldTopology.allClusters {
+		clusters[clusterName] = info
+	}
+	for clusterName, info := range newTopology.allClusters {
+		clusters[clusterName] = info
+	}
+
+	var events []TopologyChangeEvent
+	for _, clusterName := range sortedClusterNames(clusters) {
+		oldInfo, oldOK := oldTopology.allClusters[clusterName]
+		newInfo, newOK := newTopology.allClusters[clusterName]
+		event := TopologyChangeEvent{
+			Timestamp:   now,
+			ClusterName: clusterName,
+		}
+		switch {
+		case !oldOK:
+			event.Type = TopologyChangeTypeClusterAdded
+			event.NewValue = newInfo
+		case !newOK:
+			event.Type = TopologyChangeTypeClusterRemoved
+			event.OldValue = oldInfo
+		case oldInfo.Enabled != newInfo.Enabled:
+			event.Type = TopologyChangeTypeClusterDisabled
+			if newInfo.Enabled {
+				event.Type = TopologyChangeTypeClusterEnabled
+			}
+			event.OldValue = oldInfo.Enabled
+			event.NewValue = newInfo.Enabled
+			events = append(events, event)
+
+			otherInfo := oldInfo
+			otherInfo.Enabled = newInfo.Enabled
+			if reflect.DeepEqual(otherInfo, newInfo) {
+				continue
+			}
+			event = TopologyChangeEvent{
+				Timestamp:   now,
+				Type:        TopologyChangeTypeClusterUpdated,
+				ClusterName: clusterName,
+				OldValue:    oldInfo,
+				NewValue:    newInfo,
+			}
+		case !reflect.DeepEqual(oldInfo, newInfo):
+			event.Type = TopologyChangeTypeClusterUpdated
+			event.OldValue = oldInfo
+			event.NewValue = newInfo
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if oldTopology.primaryClusterName != newTopology.primaryClusterName {
+		events = append(events, TopologyChangeEvent{
+			Timestamp:   now,
+			Type:        TopologyChangeTypePrimaryClusterChanged,
+			ClusterName: newTopology.primaryClusterName,
+			OldValue:    oldTopology.primaryClusterName,
+			NewValue:    newTopology.primaryClusterName,
+		})
+	}
+	return events
+}
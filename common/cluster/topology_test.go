@@ -0,0 +1,440 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/cadence/common/clock"
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/service"
+)
+
+type recordingTopologyChangeSink struct {
+	events []TopologyChangeEvent
+}
+
+func (s *recordingTopologyChangeSink) RecordChange(event TopologyChangeEvent) {
+	s.events = append(s.events, event)
+}
+
+func TestMetadataUpdateClusterInformation(t *testing.T) {
+	now := time.Now()
+	sink := &recordingTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+		WithTimeSource(clock.NewEventTimeSource().Update(now)),
+	)
+	// copies observe the update as well
+	metadataCopy := metadata
+
+	alternativeInfo := TestAllClusterInfo[TestAlternativeClusterName]
+	disabledInfo := TestAllClusterInfo[TestDisabledClusterName]
+	enabledInfo := disabledInfo
+	enabledInfo.Enabled = true
+	enabledInfo.RPCName = "cadence-frontend"
+	enabledInfo.RPCAddress = "127.0.0.1:9104"
+	updatedAlternativeInfo := alternativeInfo
+	updatedAlternativeInfo.RPCAddress = "127.0.0.1:8105"
+	newInfo := config.ClusterInformation{
+		Enabled:                true,
+		InitialFailoverVersion: 3,
+		RPCName:                "cadence-frontend",
+		RPCAddress:             "127.0.0.1:10104",
+	}
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: updatedAlternativeInfo,
+		TestDisabledClusterName:    enabledInfo,
+		"new":                      newInfo,
+	}
+
+	err := metadata.UpdateClusterInformation(TestAlternativeClusterName, clusterGroup)
+	assert.NoError(t, err)
+	assert.False(t, metadataCopy.IsPrimaryCluster())
+	assert.Equal(t, clusterGroup, metadataCopy.GetAllClusterInfo())
+	assert.Len(t, metadataCopy.GetEnabledClusterInfo(), 4)
+	assert.Len(t, metadataCopy.GetRemoteClusterInfo(), 3)
+	assert.Equal(t, "new", metadataCopy.ClusterNameForFailoverVersion(TestFailoverVersionIncrement+3))
+
+	assert.Equal(t, []TopologyChangeEvent{
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypeClusterEnabled,
+			ClusterName: TestDisabledClusterName,
+			OldValue:    false,
+			NewValue:    true,
+		},
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypeClusterUpdated,
+			ClusterName: TestDisabledClusterName,
+			OldValue:    disabledInfo,
+			NewValue:    enabledInfo,
+		},
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypeClusterAdded,
+			ClusterName: "new",
+			NewValue:    newInfo,
+		},
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypeClusterUpdated,
+			ClusterName: TestAlternativeClusterName,
+			OldValue:    alternativeInfo,
+			NewValue:    updatedAlternativeInfo,
+		},
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypePrimaryClusterChanged,
+			ClusterName: TestAlternativeClusterName,
+			OldValue:    TestCurrentClusterName,
+			NewValue:    TestAlternativeClusterName,
+		},
+	}, sink.events)
+
+	sink.events = nil
+	delete(clusterGroup, "new")
+	assert.NoError(t, metadata.UpdateClusterInformation(TestAlternativeClusterName, clusterGroup))
+	assert.Equal(t, []TopologyChangeEvent{
+		{
+			Timestamp:   now,
+			Type:        TopologyChangeTypeClusterRemoved,
+			ClusterName: "new",
+			OldValue:    newInfo,
+		},
+	}, sink.events)
+}
+
+func TestMetadataUpdateClusterInformationInvalid(t *testing.T) {
+	sink := &recordingTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+	)
+
+	err := metadata.UpdateClusterInformation("unknown", map[string]config.ClusterInformation{
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		"duplicate":                TestAllClusterInfo[TestAlternativeClusterName],
+		"out-of-range":             {InitialFailoverVersion: TestFailoverVersionIncrement},
+	})
+	assert.EqualError(t, err, "primary cluster unknown is not specified in the cluster group; "+
+		"current cluster active is not specified in the cluster group; "+
+		"cluster out-of-range: version increment 10 is smaller than initial version: 10; "+
+		"initial versions of the cluster group have duplicates; "+
+		"clusters duplicate, standby share the rpc address 127.0.0.1:8104")
+	assert.True(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, TestAllClusterInfo, metadata.GetAllClusterInfo())
+	assert.Empty(t, sink.events)
+}
+
+func TestMetadataSetClusterEnabled(t *testing.T) {
+	sink := &recordingTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		testClusterGroupWithDisabledRPC(),
+		WithTopologyChangeSink(sink),
+	)
+
+	assert.NoError(t, metadata.SetClusterEnabled(TestDisabledClusterName, true))
+	assert.Len(t, metadata.GetEnabledClusterInfo(), 3)
+	assert.Equal(t, TestDisabledClusterName, metadata.ClusterNameForFailoverVersion(TestDisabledClusterInitialFailoverVersion))
+	assert.Len(t, sink.events, 1)
+	assert.Equal(t, TopologyChangeTypeClusterEnabled, sink.events[0].Type)
+	assert.False(t, TestAllClusterInfo[TestDisabledClusterName].Enabled)
+
+	assert.EqualError(t, metadata.SetClusterEnabled("unknown", true), "cluster unknown is not specified in the cluster group")
+	assert.Len(t, sink.events, 1)
+
+	// the enabled cluster must be valid like in the config validation, which requires an rpc name and address
+	metadata = NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+	assert.EqualError(t, metadata.SetClusterEnabled(TestDisabledClusterName, true), "cluster disabled: rpc name / address is empty")
+	assert.Len(t, metadata.GetEnabledClusterInfo(), 2)
+}
+
+func TestMetadataUpdateClusterInformationTooManyClusters(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	clusterGroup := largeClusterGroup(int(TestFailoverVersionIncrement)+2, 1)
+	clusterGroup[TestCurrentClusterName] = TestAllClusterInfo[TestCurrentClusterName]
+	err := metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster group has 13 clusters, more than version increment 10, use a larger version increment")
+	assert.Equal(t, TestAllClusterInfo, metadata.GetAllClusterInfo())
+}
+
+func TestMetadataCanRemoveCluster(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"primary":   {Enabled: true, InitialFailoverVersion: 0, ReplicaClusters: []string{"current"}},
+		"current":   {Enabled: true, InitialFailoverVersion: 1, ReplicaClusters: []string{"primary", "target"}},
+		"target":    {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"primary"}},
+		"removable": {Enabled: true, InitialFailoverVersion: 3, ReplicaClusters: []string{"primary"}},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "primary", "current", clusterGroup)
+
+	tests := []struct {
+		clusterName string
+		reasons     []string
+	}{
+		{clusterName: "primary", reasons: []string{
+			"cluster primary is the primary cluster",
+			"cluster primary is a replica cluster of current",
+			"cluster primary is a replica cluster of removable",
+			"cluster primary is a replica cluster of target",
+		}},
+		{clusterName: "current", reasons: []string{
+			"cluster current is the current cluster",
+			"cluster current is a replica cluster of primary",
+		}},
+		{clusterName: "target", reasons: []string{"cluster target is a replica cluster of current"}},
+		{clusterName: "unknown", reasons: []string{"cluster unknown is not specified in the cluster group"}},
+		{clusterName: "removable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clusterName, func(t *testing.T) {
+			ok, reasons := metadata.CanRemoveCluster(tt.clusterName)
+			assert.Equal(t, len(tt.reasons) == 0, ok)
+			assert.Equal(t, tt.reasons, reasons)
+		})
+	}
+}
+
+func TestMetadataCanRemoveClusterImplicitReplicaCluster(t *testing.T) {
+	// clusters without replica clusters replicate to all clusters, disabled clusters do not replicate
+	clusterGroup := map[string]config.ClusterInformation{
+		"primary":  {Enabled: true, InitialFailoverVersion: 0},
+		"other":    {Enabled: true, InitialFailoverVersion: 1},
+		"target":   {Enabled: true, InitialFailoverVersion: 2, ReplicaClusters: []string{"primary"}},
+		"disabled": {Enabled: false, InitialFailoverVersion: 3},
+	}
+	metadata := NewMetadata(TestFailoverVersionIncrement, "primary", "primary", clusterGroup)
+
+	ok, reasons := metadata.CanRemoveCluster("target")
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"cluster target is an implicit replica cluster of other, which replicates to all clusters",
+		"cluster target is an implicit replica cluster of primary, which replicates to all clusters",
+	}, reasons)
+}
+
+func TestMetadataConfigVersion(t *testing.T) {
+	clusterGroup := testClusterGroupWithDisabledRPC()
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, clusterGroup)
+	assert.Equal(t, uint64(0), metadata.ConfigVersion())
+
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	assert.Equal(t, uint64(0), metadata.ConfigVersion())
+
+	assert.NoError(t, metadata.UpdateClusterInformation(TestAlternativeClusterName, clusterGroup))
+	assert.Equal(t, uint64(1), metadata.ConfigVersion())
+
+	assert.NoError(t, metadata.SetClusterEnabled(TestDisabledClusterName, true))
+	assert.Equal(t, uint64(2), metadata.ConfigVersion())
+
+	assert.NoError(t, metadata.SetClusterEnabled(TestDisabledClusterName, true))
+	assert.Error(t, metadata.UpdateClusterInformation("unknown", TestAllClusterInfo))
+	assert.Equal(t, uint64(2), metadata.ConfigVersion())
+}
+
+func TestMetadataUpdateClusterInformationArchivalOnlyPrimary(t *testing.T) {
+	metadata := NewMetadata(TestFailoverVersionIncrement, TestCurrentClusterName, TestCurrentClusterName, TestAllClusterInfo)
+
+	archivalInfo := TestAllClusterInfo[TestAlternativeClusterName]
+	archivalInfo.ArchivalOnly = true
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: archivalInfo,
+	}
+	err := metadata.UpdateClusterInformation(TestAlternativeClusterName, clusterGroup)
+	assert.EqualError(t, err, "primary cluster standby is archival only")
+	assert.True(t, metadata.IsPrimaryCluster())
+
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+}
+
+func TestMetadataPromotePrimary(t *testing.T) {
+	sink := &recordingTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+	)
+
+	assert.NoError(t, metadata.PromotePrimary(TestCurrentClusterName))
+	assert.Empty(t, sink.events)
+	assert.Equal(t, uint64(0), metadata.ConfigVersion())
+
+	assert.EqualError(t, metadata.PromotePrimary(TestDisabledClusterName), "cluster disabled is not enabled")
+	assert.EqualError(t, metadata.PromotePrimary("unknown"), "cluster unknown is not specified in the cluster group")
+	assert.True(t, metadata.IsPrimaryCluster())
+
+	assert.NoError(t, metadata.PromotePrimary(TestAlternativeClusterName))
+	assert.False(t, metadata.IsPrimaryCluster())
+	assert.Equal(t, TestAlternativeClusterName, metadata.ActiveClusterName())
+	assert.Equal(t, TestAllClusterInfo, metadata.GetAllClusterInfo())
+	assert.Len(t, sink.events, 1)
+	assert.Equal(t, TopologyChangeTypePrimaryClusterChanged, sink.events[0].Type)
+	assert.Equal(t, TestCurrentClusterName, sink.events[0].OldValue)
+	assert.Equal(t, TestAlternativeClusterName, sink.events[0].NewValue)
+}
+
+type lockedTopologyChangeSink struct {
+	sync.Mutex
+	events []TopologyChangeEvent
+}
+
+func (s *lockedTopologyChangeSink) RecordChange(event TopologyChangeEvent) {
+	s.Lock()
+	defer s.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *lockedTopologyChangeSink) getEvents() []TopologyChangeEvent {
+	s.Lock()
+	defer s.Unlock()
+	return append([]TopologyChangeEvent(nil), s.events...)
+}
+
+func TestMetadataUpdateClusterInformationDebounce(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	timeSource := clock.NewEventTimeSource().Update(now)
+	sink := &lockedTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+		WithTopologyChangeDebounce(100*time.Millisecond),
+		WithTimeSource(timeSource),
+	)
+	defer metadata.Close()
+
+	clusterGroup := make(map[string]config.ClusterInformation, len(TestAllClusterInfo))
+	for clusterName, info := range TestAllClusterInfo {
+		clusterGroup[clusterName] = info
+	}
+	for _, address := range []string{"127.0.0.1:9104", "127.0.0.1:10104", "127.0.0.1:11104"} {
+		standby := clusterGroup[TestAlternativeClusterName]
+		standby.RPCAddress = address
+		clusterGroup[TestAlternativeClusterName] = standby
+		assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+		// the topology is updated immediately, only the changes are reported later
+		assert.Equal(t, address, metadata.GetAllClusterInfo()[TestAlternativeClusterName].RPCAddress)
+		// each update restarts the window
+		now = now.Add(60 * time.Millisecond)
+		timeSource.Update(now)
+	}
+	assert.Empty(t, sink.getEvents())
+	assert.Equal(t, uint64(3), metadata.ConfigVersion())
+
+	now = now.Add(40 * time.Millisecond)
+	timeSource.Update(now)
+	events := sink.getEvents()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, now, events[0].Timestamp)
+		assert.Equal(t, TopologyChangeTypeClusterUpdated, events[0].Type)
+		assert.Equal(t, TestAlternativeClusterName, events[0].ClusterName)
+		assert.Equal(t, TestAlternativeClusterFrontendAddress, events[0].OldValue.(config.ClusterInformation).RPCAddress)
+		assert.Equal(t, "127.0.0.1:11104", events[0].NewValue.(config.ClusterInformation).RPCAddress)
+	}
+
+	// updates reverting each other within the window report no change
+	standby := clusterGroup[TestAlternativeClusterName]
+	standby.RPCAddress = TestAlternativeClusterFrontendAddress
+	clusterGroup[TestAlternativeClusterName] = standby
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	standby.RPCAddress = "127.0.0.1:11104"
+	clusterGroup[TestAlternativeClusterName] = standby
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	now = now.Add(time.Second)
+	timeSource.Update(now)
+	assert.Len(t, sink.getEvents(), 1)
+}
+
+func TestMetadataCloseStopsTopologyChangeDebounce(t *testing.T) {
+	timeSource := clock.NewEventTimeSource().Update(time.Unix(1600000000, 0))
+	sink := &lockedTopologyChangeSink{}
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithTopologyChangeSink(sink),
+		WithTopologyChangeDebounce(100*time.Millisecond),
+		WithTimeSource(timeSource),
+	)
+
+	clusterGroup := make(map[string]config.ClusterInformation, len(TestAllClusterInfo))
+	for clusterName, info := range TestAllClusterInfo {
+		clusterGroup[clusterName] = info
+	}
+	standby := clusterGroup[TestAlternativeClusterName]
+	standby.RPCAddress = "127.0.0.1:9104"
+	clusterGroup[TestAlternativeClusterName] = standby
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	assert.Empty(t, sink.getEvents())
+
+	// the pending changes are reported on close, the stopped timer does not report them again
+	metadata.Close()
+	assert.Len(t, sink.getEvents(), 1)
+	timeSource.Update(time.Unix(1600000001, 0))
+	assert.Len(t, sink.getEvents(), 1)
+
+	// changes after close are reported immediately
+	standby.RPCAddress = "127.0.0.1:10104"
+	clusterGroup[TestAlternativeClusterName] = standby
+	assert.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	assert.Len(t, sink.getEvents(), 2)
+	metadata.Close()
+	assert.Len(t, sink.getEvents(), 2)
+}
+
+// testClusterGroupWithDisabledRPC returns a copy of TestAllClusterInfo whose disabled cluster has RPC settings,
+// so that it can be enabled
+func testClusterGroupWithDisabledRPC() map[string]config.ClusterInformation {
+	clusterGroup := make(map[string]config.ClusterInformation, len(TestAllClusterInfo))
+	for clusterName, info := range TestAllClusterInfo {
+		clusterGroup[clusterName] = info
+	}
+	disabled := clusterGroup[TestDisabledClusterName]
+	disabled.RPCName = service.Frontend
+	disabled.RPCAddress = "127.0.0.1:9104"
+	disabled.RPCTransport = TestClusterXDCTransport
+	clusterGroup[TestDisabledClusterName] = disabled
+	return clusterGroup
+}
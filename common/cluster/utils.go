@@ -41,3 +41,13 @@ func GetOrUseDefaultClusters(currentClusterName string, clusters []*persistence.
 	}
 	return clusters
 }
+
+// SuggestFailoverVersionIncrement return a failover version increment for the expected number of clusters,
+// the smallest power of ten leaving room for at least twice as many clusters, and at least 10
+func SuggestFailoverVersionIncrement(expectedClusters int) int64 {
+	increment := int64(10)
+	for increment < 2*int64(expectedClusters) {
+		increment *= 10
+	}
+	return increment
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"go.uber.org/multierr"
+
+	"github.com/uber/cadence/common/config"
+)
+
+type (
+	// MetadataValidator validates a Metadata, e.g. to enforce deployment specific naming conventions
+	MetadataValidator func(m Metadata) error
+)
+
+var (
+	metadataValidatorsLock sync.RWMutex
+	metadataValidators     []MetadataValidator
+)
+
+// RegisterMetadataValidator registers a validator run by NewValidatedMetadata and UpdateClusterInformation
+// It is meant to be called during service initialization
+func RegisterMetadataValidator(validator MetadataValidator) {
+	metadataValidatorsLock.Lock()
+	defer metadataValidatorsLock.Unlock()
+	metadataValidators = append(metadataValidators, validator)
+}
+
+// NewValidatedMetadata validates the cluster group config and creates a new instance of Metadata from it,
+// all registered validators are run against the created Metadata and their errors aggregated.
+// The cluster group must contain at least the current cluster, an empty cluster group is rejected.
+// The cluster information transform, if any, is applied before validation, so the transformed config is validated.
+func NewValidatedMetadata(clusterGroupMetadata *config.ClusterGroupMetadata, opts ...Option) (Metadata, error) {
+	if clusterGroupMetadata == nil {
+		return Metadata{}, clusterGroupMetadata.Validate()
+	}
+	if len(clusterGroupMetadata.ClusterGroup) == 0 {
+		return Metadata{}, fmt.Errorf(
+			"cluster group is empty, it must contain at least the current cluster %v",
+			clusterGroupMetadata.CurrentClusterName,
+		)
+	}
+
+	opts = append([]Option{WithPrimaryFailoverOrder(clusterGroupMetadata.PrimaryFailoverOrder)}, opts...)
+	m, err := newMetadata(
+		clusterGroupMetadata.FailoverVersionIncrement,
+		clusterGroupMetadata.PrimaryClusterName,
+		clusterGroupMetadata.CurrentClusterName,
+		clusterGroupMetadata.ClusterGroup,
+		opts...,
+	)
+	if err != nil {
+		return Metadata{}, err
+	}
+	transformed := *clusterGroupMetadata
+	transformed.ClusterGroup = m.getTopology().allClusters
+	if err := transformed.Validate(); err != nil {
+		return Metadata{}, err
+	}
+	if err := m.validateReservedTestResidues(transformed.ClusterGroup); err != nil {
+		return Metadata{}, err
+	}
+	if err := runMetadataValidators(m); err != nil {
+		return Metadata{}, err
+	}
+	return m, nil
+}
+
+// ValidateIncrementForClusters validates that the initial failover versions of the cluster group are still unique
+// modulo the proposed new failover version increment, i.e. failover versions keep resolving to the same clusters
+func ValidateIncrementForClusters(clusterGroup map[string]config.ClusterInformation, newIncrement int64) error {
+	if newIncrement <= 0 {
+		return fmt.Errorf("version increment %v is not positive", newIncrement)
+	}
+
+	var errs error
+	residueToClusterName := make(map[int64]string, len(clusterGroup))
+	for _, clusterName := range sortedClusterNames(clusterGroup) {
+		residue := clusterGroup[clusterName].InitialFailoverVersion % newIncrement
+		if other, ok := residueToClusterName[residue]; ok {
+			errs = multierr.Append(errs, fmt.Errorf(
+				"clusters %v and %v collide on residue %v with version increment %v",
+				other,
+				clusterName,
+				residue,
+				newIncrement,
+			))
+			continue
+		}
+		residueToClusterName[residue] = clusterName
+	}
+	return errs
+}
+
+// ValidateCurrentClusterInitialVersion is a MetadataValidator rejecting an initial failover version of the current cluster
+// not within [0, increment), or of 0 unless allowed with AllowZeroInitialFailoverVersion, as every failover version
+// stamped by the current cluster would resolve to another cluster. The errors wrap
+// ErrCurrentClusterInitialVersionOutOfRange. Register it with RegisterMetadataValidator to enforce it.
+func ValidateCurrentClusterInitialVersion(m Metadata) error {
+	info, ok := m.getTopology().allClusters[m.currentClusterName]
+	if !ok {
+		return nil
+	}
+
+	if info.InitialFailoverVersion < 0 || info.InitialFailoverVersion >= m.failoverVersionIncrement {
+		return fmt.Errorf(
+			"%w: cluster %v: initial version %v is not within [0, %v)",
+			ErrCurrentClusterInitialVersionOutOfRange,
+			m.currentClusterName,
+			info.InitialFailoverVersion,
+			m.failoverVersionIncrement,
+		)
+	}
+	if info.InitialFailoverVersion == 0 && !info.AllowZeroInitialFailoverVersion {
+		return fmt.Errorf(
+			"%w: cluster %v: initial version is 0 without allowZeroInitialFailoverVersion",
+			ErrCurrentClusterInitialVersionOutOfRange,
+			m.currentClusterName,
+		)
+	}
+	return nil
+}
+
+// ClustersMissingInitialVersion return the sorted names of the enabled clusters with an initial failover version of 0
+// not marked as deliberate with AllowZeroInitialFailoverVersion, which may have omitted the initial failover version
+func (m Metadata) ClustersMissingInitialVersion() []string {
+	clusters := make(map[string]config.ClusterInformation)
+	for clusterName, info := range m.getTopology().getEnabledClusters() {
+		if info.InitialFailoverVersion == 0 && !info.AllowZeroInitialFailoverVersion {
+			clusters[clusterName] = info
+		}
+	}
+	return sortedClusterNames(clusters)
+}
+
+// MinimalCompatibleIncrement return the smallest power of ten, and at least 10, usable as failover version increment
+// for the cluster group, i.e. with all initial failover versions within range and therefore distinct residues.
+// It fails if the initial failover versions collide, as they then collide under every increment.
+func MinimalCompatibleIncrement(clusterGroup map[string]config.ClusterInformation) (int64, error) {
+	if len(clusterGroup) == 0 {
+		return 0, errors.New("empty cluster group")
+	}
+
+	var errs error
+	maxInitialFailoverVersion := int64(0)
+	for _, clusterName := range sortedClusterNames(clusterGroup) {
+		initialFailoverVersion := clusterGroup[clusterName].InitialFailoverVersion
+		if initialFailoverVersion < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("cluster %v: initial version %v is negative", clusterName, initialFailoverVersion))
+		}
+		if initialFailoverVersion > maxInitialFailoverVersion {
+			maxInitialFailoverVersion = initialFailoverVersion
+		}
+	}
+	if errs != nil {
+		return 0, errs
+	}
+
+	increment := int64(10)
+	for increment <= maxInitialFailoverVersion {
+		if increment > math.MaxInt64/10 {
+			return 0, fmt.Errorf("initial version %v is too large for a power of ten increment", maxInitialFailoverVersion)
+		}
+		increment *= 10
+	}
+	if err := ValidateIncrementForClusters(clusterGroup, increment); err != nil {
+		return 0, err
+	}
+	return increment, nil
+}
+
+// transformClusterGroup returns a copy of the cluster group with the cluster information transform applied, if any
+func (m Metadata) transformClusterGroup(
+	clusterGroup map[string]config.ClusterInformation,
+) (map[string]config.ClusterInformation, error) {
+	transformed := make(map[string]config.ClusterInformation, len(clusterGroup))
+	for clusterName, info := range clusterGroup {
+		if m.clusterInformationTransform != nil {
+			var err error
+			info, err = m.clusterInformationTransform(clusterName, info)
+			if err != nil {
+				return nil, fmt.Errorf("cluster %v: transform cluster information: %w", clusterName, err)
+			}
+		}
+		transformed[clusterName] = info
+	}
+	return transformed, nil
+}
+
+func runMetadataValidators(m Metadata) error {
+	metadataValidatorsLock.RLock()
+	defer metadataValidatorsLock.RUnlock()
+
+	var errs error
+	for _, validator := range metadataValidators {
+		errs = multierr.Append(errs, validator(m))
+	}
+	return errs
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2022 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestNewValidatedMetadata(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}
+	metadata, err := NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestAlternativeClusterName,
+		ClusterGroup:             clusterGroup,
+		PrimaryFailoverOrder:     []string{TestAlternativeClusterName},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TestAlternativeClusterName, metadata.GetCurrentClusterName())
+	assert.False(t, metadata.IsPrimaryCluster())
+	candidate, ok := metadata.NextPrimaryCandidate()
+	assert.True(t, ok)
+	assert.Equal(t, TestAlternativeClusterName, candidate)
+
+	_, err = NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       "unknown",
+		CurrentClusterName:       TestAlternativeClusterName,
+		ClusterGroup:             clusterGroup,
+	})
+	assert.EqualError(t, err, "primary cluster is not specified in the cluster group")
+}
+
+func TestNewValidatedMetadataEmptyClusterGroup(t *testing.T) {
+	for _, clusterGroup := range []map[string]config.ClusterInformation{nil, {}} {
+		_, err := NewValidatedMetadata(&config.ClusterGroupMetadata{
+			FailoverVersionIncrement: TestFailoverVersionIncrement,
+			PrimaryClusterName:       TestCurrentClusterName,
+			CurrentClusterName:       TestCurrentClusterName,
+			ClusterGroup:             clusterGroup,
+		})
+		assert.EqualError(t, err, "cluster group is empty, it must contain at least the current cluster active")
+	}
+}
+
+func TestRegisterMetadataValidator(t *testing.T) {
+	defer func(validators []MetadataValidator) {
+		metadataValidators = validators
+	}(metadataValidators)
+
+	RegisterMetadataValidator(func(m Metadata) error {
+		for clusterName := range m.GetAllClusterInfo() {
+			if strings.HasPrefix(clusterName, "tmp-") {
+				return fmt.Errorf("cluster %v: temporary clusters are not allowed", clusterName)
+			}
+		}
+		return nil
+	})
+	RegisterMetadataValidator(func(m Metadata) error {
+		if len(m.GetAllClusterInfo()) > 2 {
+			return fmt.Errorf("at most 2 clusters are allowed")
+		}
+		return nil
+	})
+
+	clusterGroupMetadata := &config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup:             TestSingleDCClusterInfo,
+	}
+	metadata, err := NewValidatedMetadata(clusterGroupMetadata)
+	require.NoError(t, err)
+
+	clusterGroupMetadata.ClusterGroup = map[string]config.ClusterInformation{
+		TestCurrentClusterName: TestAllClusterInfo[TestCurrentClusterName],
+		"tmp-standby":          TestAllClusterInfo[TestAlternativeClusterName],
+		"tmp-other": {
+			InitialFailoverVersion: TestDisabledClusterInitialFailoverVersion,
+			RPCTransport:           TestClusterXDCTransport,
+		},
+	}
+	_, err = NewValidatedMetadata(clusterGroupMetadata)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "temporary clusters are not allowed")
+	assert.Contains(t, err.Error(), "at most 2 clusters are allowed")
+
+	err = metadata.UpdateClusterInformation(TestCurrentClusterName, map[string]config.ClusterInformation{
+		TestCurrentClusterName: TestAllClusterInfo[TestCurrentClusterName],
+		"tmp-standby":          TestAllClusterInfo[TestAlternativeClusterName],
+	})
+	assert.EqualError(t, err, "cluster tmp-standby: temporary clusters are not allowed")
+	assert.Equal(t, TestSingleDCClusterInfo, metadata.GetAllClusterInfo())
+}
+
+func TestNewValidatedMetadataClusterInformationTransform(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+		TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+	}
+	clusterGroupMetadata := &config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup:             clusterGroup,
+	}
+	rewriteRPCAddress := func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error) {
+		info.RPCAddress = clusterName + ".example.com:7833"
+		return info, nil
+	}
+
+	metadata, err := NewValidatedMetadata(clusterGroupMetadata, WithClusterInformationTransform(rewriteRPCAddress))
+	require.NoError(t, err)
+	for clusterName, info := range metadata.GetAllClusterInfo() {
+		assert.Equal(t, clusterName+".example.com:7833", info.RPCAddress)
+	}
+	assert.Equal(t, TestAllClusterInfo[TestCurrentClusterName].RPCAddress, clusterGroup[TestCurrentClusterName].RPCAddress)
+
+	clusterGroup["new"] = config.ClusterInformation{Enabled: true, InitialFailoverVersion: 3, RPCName: "cadence-frontend"}
+	require.NoError(t, metadata.UpdateClusterInformation(TestCurrentClusterName, clusterGroup))
+	assert.Equal(t, "new.example.com:7833", metadata.GetAllClusterInfo()["new"].RPCAddress)
+	delete(clusterGroup, "new")
+
+	failingTransform := func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error) {
+		if clusterName == TestAlternativeClusterName {
+			return info, fmt.Errorf("secret not found")
+		}
+		return info, nil
+	}
+	_, err = NewValidatedMetadata(clusterGroupMetadata, WithClusterInformationTransform(failingTransform))
+	assert.EqualError(t, err, "cluster standby: transform cluster information: secret not found")
+	assert.PanicsWithValue(t, "cluster standby: transform cluster information: secret not found", func() {
+		NewMetadata(
+			TestFailoverVersionIncrement,
+			TestCurrentClusterName,
+			TestCurrentClusterName,
+			clusterGroup,
+			WithClusterInformationTransform(failingTransform),
+		)
+	})
+
+	// the transformed config is validated rather than the loaded one
+	clearRPCAddress := func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error) {
+		info.RPCAddress = ""
+		return info, nil
+	}
+	_, err = NewValidatedMetadata(clusterGroupMetadata, WithClusterInformationTransform(clearRPCAddress))
+	assert.EqualError(t, err, "cluster active: rpc name / address is empty; cluster standby: rpc name / address is empty")
+	placeholderGroup := map[string]config.ClusterInformation{
+		TestCurrentClusterName: clusterGroup[TestCurrentClusterName],
+		TestAlternativeClusterName: func() config.ClusterInformation {
+			info := clusterGroup[TestAlternativeClusterName]
+			info.RPCAddress = ""
+			return info
+		}(),
+	}
+	placeholderMetadata := *clusterGroupMetadata
+	placeholderMetadata.ClusterGroup = placeholderGroup
+	metadata, err = NewValidatedMetadata(&placeholderMetadata, WithClusterInformationTransform(rewriteRPCAddress))
+	require.NoError(t, err)
+	assert.Equal(t, "standby.example.com:7833", metadata.GetAllClusterInfo()[TestAlternativeClusterName].RPCAddress)
+}
+
+func TestNewMetadataClusterInformationTransform(t *testing.T) {
+	rewriteRPCAddress := func(clusterName string, info config.ClusterInformation) (config.ClusterInformation, error) {
+		info.RPCAddress = clusterName + ".example.com:7833"
+		return info, nil
+	}
+
+	metadata := NewMetadata(
+		TestFailoverVersionIncrement,
+		TestCurrentClusterName,
+		TestCurrentClusterName,
+		TestAllClusterInfo,
+		WithClusterInformationTransform(rewriteRPCAddress),
+	)
+	for clusterName, info := range metadata.GetAllClusterInfo() {
+		assert.Equal(t, clusterName+".example.com:7833", info.RPCAddress)
+	}
+	assert.Equal(t, "127.0.0.1:7104", TestAllClusterInfo[TestCurrentClusterName].RPCAddress)
+}
+
+func TestValidateIncrementForClusters(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"a": {InitialFailoverVersion: 1},
+		"b": {InitialFailoverVersion: 2},
+		"c": {InitialFailoverVersion: 11},
+		"d": {InitialFailoverVersion: 12},
+	}
+
+	assert.NoError(t, ValidateIncrementForClusters(clusterGroup, 100))
+	assert.NoError(t, ValidateIncrementForClusters(clusterGroup, 20))
+	assert.EqualError(
+		t,
+		ValidateIncrementForClusters(clusterGroup, 10),
+		"clusters a and c collide on residue 1 with version increment 10; clusters b and d collide on residue 2 with version increment 10",
+	)
+	assert.EqualError(t, ValidateIncrementForClusters(clusterGroup, 0), "version increment 0 is not positive")
+}
+
+func TestMinimalCompatibleIncrement(t *testing.T) {
+	group := func(initialFailoverVersions ...int64) map[string]config.ClusterInformation {
+		clusterGroup := make(map[string]config.ClusterInformation, len(initialFailoverVersions))
+		for i, initialFailoverVersion := range initialFailoverVersions {
+			clusterGroup[fmt.Sprintf("cluster-%v", i)] = config.ClusterInformation{InitialFailoverVersion: initialFailoverVersion}
+		}
+		return clusterGroup
+	}
+
+	tests := []struct {
+		msg          string
+		clusterGroup map[string]config.ClusterInformation
+		increment    int64
+		err          string
+	}{
+		{
+			msg:          "single cluster",
+			clusterGroup: group(0),
+			increment:    10,
+		},
+		{
+			msg:          "fits in 10",
+			clusterGroup: group(0, 1, 9),
+			increment:    10,
+		},
+		{
+			msg:          "initial version equal to power of ten",
+			clusterGroup: group(0, 10),
+			increment:    100,
+		},
+		{
+			msg:          "sparse initial versions",
+			clusterGroup: group(2, 150, 999),
+			increment:    1000,
+		},
+		{
+			msg:          "duplicate initial versions",
+			clusterGroup: group(1, 5, 5),
+			err:          "clusters cluster-1 and cluster-2 collide on residue 5 with version increment 10",
+		},
+		{
+			msg:          "negative initial version",
+			clusterGroup: group(0, -1),
+			err:          "cluster cluster-1: initial version -1 is negative",
+		},
+		{
+			msg:          "too large initial version",
+			clusterGroup: group(math.MaxInt64),
+			err:          "initial version 9223372036854775807 is too large for a power of ten increment",
+		},
+		{
+			msg: "empty cluster group",
+			err: "empty cluster group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			increment, err := MinimalCompatibleIncrement(tt.clusterGroup)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.increment, increment)
+			assert.NoError(t, ValidateIncrementForClusters(tt.clusterGroup, increment))
+		})
+	}
+}
+
+func TestMetadataClustersMissingInitialVersion(t *testing.T) {
+	omitted := NewMetadata(TestFailoverVersionIncrement, "configured", "configured", map[string]config.ClusterInformation{
+		"omitted":    {Enabled: true},
+		"configured": {Enabled: true, InitialFailoverVersion: 1},
+	})
+	assert.Equal(t, []string{"omitted"}, omitted.ClustersMissingInitialVersion())
+
+	deliberate := NewMetadata(TestFailoverVersionIncrement, "configured", "configured", map[string]config.ClusterInformation{
+		"deliberate": {Enabled: true, AllowZeroInitialFailoverVersion: true},
+		"configured": {Enabled: true, InitialFailoverVersion: 1},
+	})
+	assert.Empty(t, deliberate.ClustersMissingInitialVersion())
+
+	disabled := NewMetadata(TestFailoverVersionIncrement, "configured", "configured", map[string]config.ClusterInformation{
+		"disabled":   {Enabled: false},
+		"configured": {Enabled: true, InitialFailoverVersion: 1},
+	})
+	assert.Empty(t, disabled.ClustersMissingInitialVersion())
+
+	assert.Equal(t, []string{TestCurrentClusterName}, GetTestClusterMetadata(true).ClustersMissingInitialVersion())
+}
+
+func TestValidateCurrentClusterInitialVersion(t *testing.T) {
+	clusterGroup := map[string]config.ClusterInformation{
+		"zero":     {Enabled: true, InitialFailoverVersion: 0},
+		"allowed":  {Enabled: true, InitialFailoverVersion: 0, AllowZeroInitialFailoverVersion: true},
+		"standby":  {Enabled: true, InitialFailoverVersion: 1},
+		"overflow": {Enabled: true, InitialFailoverVersion: TestFailoverVersionIncrement + 2},
+		"negative": {Enabled: true, InitialFailoverVersion: -1},
+	}
+	for currentClusterName, expectedErr := range map[string]string{
+		"allowed":  "",
+		"standby":  "",
+		"zero":     "current cluster initial version is out of range: cluster zero: initial version is 0 without allowZeroInitialFailoverVersion",
+		"overflow": "current cluster initial version is out of range: cluster overflow: initial version 12 is not within [0, 10)",
+		"negative": "current cluster initial version is out of range: cluster negative: initial version -1 is not within [0, 10)",
+	} {
+		metadata := NewMetadata(TestFailoverVersionIncrement, "standby", currentClusterName, clusterGroup)
+		err := ValidateCurrentClusterInitialVersion(metadata)
+		if expectedErr == "" {
+			assert.NoError(t, err, currentClusterName)
+			continue
+		}
+		assert.EqualError(t, err, expectedErr)
+		assert.ErrorIs(t, err, ErrCurrentClusterInitialVersionOutOfRange)
+	}
+
+	defer func(validators []MetadataValidator) {
+		metadataValidators = validators
+	}(metadataValidators)
+	RegisterMetadataValidator(ValidateCurrentClusterInitialVersion)
+
+	_, err := NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestCurrentClusterName,
+		ClusterGroup:             TestSingleDCClusterInfo,
+	})
+	assert.ErrorIs(t, err, ErrCurrentClusterInitialVersionOutOfRange)
+	_, err = NewValidatedMetadata(&config.ClusterGroupMetadata{
+		FailoverVersionIncrement: TestFailoverVersionIncrement,
+		PrimaryClusterName:       TestCurrentClusterName,
+		CurrentClusterName:       TestAlternativeClusterName,
+		ClusterGroup: map[string]config.ClusterInformation{
+			TestCurrentClusterName:     TestAllClusterInfo[TestCurrentClusterName],
+			TestAlternativeClusterName: TestAllClusterInfo[TestAlternativeClusterName],
+		},
+	})
+	assert.NoError(t, err)
+}
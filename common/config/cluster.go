@@ -24,6 +24,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"go.uber.org/yarpc/transport/grpc"
 	"go.uber.org/yarpc/transport/tchannel"
@@ -42,6 +45,9 @@ type (
 		PrimaryClusterName string `yaml:"primaryClusterName"`
 		// Deprecated: please use PrimaryClusterName
 		MasterClusterName string `yaml:"masterClusterName"`
+		// PrimaryFailoverOrder is the ordered list of clusters to take over as primary cluster
+		// if the configured primary cluster becomes unavailable
+		PrimaryFailoverOrder []string `yaml:"primaryFailoverOrder"`
 		// CurrentClusterName is the name of the cluster of current deployment
 		CurrentClusterName string `yaml:"currentClusterName"`
 		// ClusterRedirectionPolicy contains the cluster redirection policy for global domains
@@ -56,21 +62,51 @@ type (
 	// ClusterInformation contains the information about each cluster participating in cross DC
 	ClusterInformation struct {
 		Enabled bool `yaml:"enabled"`
+		// ArchivalOnly indicates the cluster only keeps archived data and can never become active for a domain
+		ArchivalOnly bool `yaml:"archivalOnly"`
 		// InitialFailoverVersion is the identifier of each cluster. 0 <= the value < failoverVersionIncrement
 		InitialFailoverVersion int64 `yaml:"initialFailoverVersion"`
+		// AllowZeroInitialFailoverVersion marks an initial failover version of 0 as deliberate rather than omitted
+		AllowZeroInitialFailoverVersion bool `yaml:"allowZeroInitialFailoverVersion"`
 		// RPCName indicate the remote service name
 		RPCName string `yaml:"rpcName"`
 		// Address indicate the remote service address(Host:Port). Host can be DNS name.
 		// For currentCluster, it's usually the same as publicClient.hostPort
 		RPCAddress string `yaml:"rpcAddress" validate:"nonzero"`
+		// DynamicDiscovery indicates the RPC address is resolved by the address resolver of the cluster metadata,
+		// e.g. from Consul. RPCAddress is used if there is no address resolver.
+		DynamicDiscovery bool `yaml:"dynamicDiscovery"`
 		// RPCTransport specifies transport to use for replication traffic.
 		// Allowed values: tchannel|grpc
-		// Default: tchannel
+		// Default: the default RPC transport of the cluster metadata, tchannel unless configured.
+		// FillDefaults sets tchannel.
 		RPCTransport string `yaml:"rpcTransport"`
 		// AuthorizationProvider contains the information to authorize the cluster
 		AuthorizationProvider AuthorizationProvider `yaml:"authorizationProvider"`
 		// TLS configures client TLS/SSL authentication for connections to this cluster
 		TLS TLS `yaml:"tls"`
+		// Tags are arbitrary labels of the cluster, e.g. region: us-east
+		Tags map[string]string `yaml:"tags"`
+		// Capabilities are the features supported by the cluster, e.g. replication message types
+		// It allows other clusters to downgrade their requests during rolling upgrades
+		Capabilities []string `yaml:"capabilities"`
+		// ReplicaClusters are the clusters this cluster replicates to, empty means all other clusters
+		ReplicaClusters []string `yaml:"replicaClusters"`
+		// AllowDisabledReplicaClusters allows replica clusters which are not enabled, e.g. while they are set up
+		AllowDisabledReplicaClusters bool `yaml:"allowDisabledReplicaClusters"`
+		// FailoverEnabled indicates whether domain failovers can be initiated from the cluster, default true
+		FailoverEnabled *bool `yaml:"failoverEnabled"`
+		// ReplicationPriority orders the clusters to replicate from, lower values first, 0 means unset and orders last
+		ReplicationPriority int `yaml:"replicationPriority"`
+		// SourceIncrement is the failover version increment the versions of the cluster were generated with,
+		// e.g. during a migration to another failover version increment. 0 means the failover version increment.
+		SourceIncrement int64 `yaml:"sourceIncrement"`
+		// MaxReplicationLag is the replication lag of the cluster to alert on, 0 means the default
+		MaxReplicationLag time.Duration `yaml:"maxReplicationLag"`
+		// QuorumMember indicates the cluster takes part in quorum based failover decisions, it must be enabled
+		QuorumMember bool `yaml:"quorumMember"`
+		// Environment is the environment of the cluster, e.g. staging or production
+		Environment string `yaml:"environment"`
 	}
 
 	AuthorizationProvider struct {
@@ -103,41 +139,173 @@ func (m *ClusterGroupMetadata) Validate() error {
 	if len(m.ClusterGroup) == 0 {
 		errs = multierr.Append(errs, errors.New("empty cluster group"))
 	}
-	if _, ok := m.ClusterGroup[m.PrimaryClusterName]; len(m.PrimaryClusterName) > 0 && !ok {
+	if info, ok := m.ClusterGroup[m.PrimaryClusterName]; len(m.PrimaryClusterName) > 0 && !ok {
 		errs = multierr.Append(errs, errors.New("primary cluster is not specified in the cluster group"))
+	} else if ok && info.ArchivalOnly {
+		errs = multierr.Append(errs, errors.New("primary cluster is archival only"))
 	}
 	if _, ok := m.ClusterGroup[m.CurrentClusterName]; len(m.CurrentClusterName) > 0 && !ok {
 		errs = multierr.Append(errs, errors.New("current cluster is not specified in the cluster group"))
 	}
 
+	for _, clusterName := range m.PrimaryFailoverOrder {
+		info, ok := m.ClusterGroup[clusterName]
+		if !ok {
+			errs = multierr.Append(errs, fmt.Errorf("primary failover candidate %v is not specified in the cluster group", clusterName))
+		} else if info.ArchivalOnly {
+			errs = multierr.Append(errs, fmt.Errorf("primary failover candidate %v is archival only", clusterName))
+		}
+	}
+
+	return multierr.Append(errs, ValidateClusterGroup(m.FailoverVersionIncrement, m.ClusterGroup))
+}
+
+// ValidateClusterGroup validates the clusters of a cluster group against the failover version increment,
+// it is shared by Validate and the runtime updates of the cluster metadata
+func ValidateClusterGroup(failoverVersionIncrement int64, clusterGroup map[string]ClusterInformation) error {
+	var errs error
+	if failoverVersionIncrement > 0 && int64(len(clusterGroup)) > failoverVersionIncrement {
+		errs = multierr.Append(errs, fmt.Errorf(
+			"cluster group has %v clusters, more than version increment %v, use a larger version increment",
+			len(clusterGroup),
+			failoverVersionIncrement,
+		))
+	}
+
 	versionToClusterName := make(map[int64]string)
-	for clusterName, info := range m.ClusterGroup {
+	for _, clusterName := range sortedClusterNames(clusterGroup) {
+		info := clusterGroup[clusterName]
 		if len(clusterName) == 0 {
 			errs = multierr.Append(errs, errors.New("cluster with empty name defined"))
 		}
 		versionToClusterName[info.InitialFailoverVersion] = clusterName
 
-		if m.FailoverVersionIncrement <= info.InitialFailoverVersion || info.InitialFailoverVersion < 0 {
+		if failoverVersionIncrement <= info.InitialFailoverVersion || info.InitialFailoverVersion < 0 {
 			errs = multierr.Append(errs, fmt.Errorf(
 				"cluster %s: version increment %v is smaller than initial version: %v",
 				clusterName,
-				m.FailoverVersionIncrement,
+				failoverVersionIncrement,
+				info.InitialFailoverVersion,
+			))
+		}
+
+		if info.SourceIncrement < 0 || (info.SourceIncrement > 0 && info.SourceIncrement <= info.InitialFailoverVersion) {
+			errs = multierr.Append(errs, fmt.Errorf(
+				"cluster %s: source increment %v is invalid for initial version: %v",
+				clusterName,
+				info.SourceIncrement,
 				info.InitialFailoverVersion,
 			))
 		}
 
+		if info.QuorumMember && !info.Enabled {
+			errs = multierr.Append(errs, fmt.Errorf("cluster %v: quorum member is not enabled", clusterName))
+		}
+		if info.MaxReplicationLag < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("cluster %v: max replication lag %v is negative", clusterName, info.MaxReplicationLag))
+		}
+
 		if info.Enabled && (len(info.RPCName) == 0 || len(info.RPCAddress) == 0) {
 			errs = multierr.Append(errs, fmt.Errorf("cluster %v: rpc name / address is empty", clusterName))
 		}
-		if info.RPCTransport != tchannel.TransportName && info.RPCTransport != grpc.TransportName {
+		if len(info.RPCTransport) > 0 && info.RPCTransport != tchannel.TransportName && info.RPCTransport != grpc.TransportName {
 			errs = multierr.Append(errs, fmt.Errorf("cluster %v: rpc transport must %v or %v",
 				clusterName, tchannel.TransportName, grpc.TransportName))
 		}
+
+		capabilities := make(map[string]struct{}, len(info.Capabilities))
+		for _, capability := range info.Capabilities {
+			if len(capability) == 0 {
+				errs = multierr.Append(errs, fmt.Errorf("cluster %v: empty capability defined", clusterName))
+			}
+			capabilities[capability] = struct{}{}
+		}
+		if len(capabilities) != len(info.Capabilities) {
+			errs = multierr.Append(errs, fmt.Errorf("cluster %v: capabilities have duplicates", clusterName))
+		}
+
+		for _, replicaCluster := range info.ReplicaClusters {
+			if replicaInfo, ok := clusterGroup[replicaCluster]; !ok {
+				errs = multierr.Append(errs, fmt.Errorf("cluster %v: replica cluster %v is not specified in the cluster group", clusterName, replicaCluster))
+			} else if !replicaInfo.Enabled && !info.AllowDisabledReplicaClusters {
+				errs = multierr.Append(errs, fmt.Errorf("cluster %v: replica cluster %v is not enabled", clusterName, replicaCluster))
+			}
+		}
 	}
-	if len(versionToClusterName) != len(m.ClusterGroup) {
+	if len(versionToClusterName) != len(clusterGroup) {
 		errs = multierr.Append(errs, errors.New("initial versions of the cluster group have duplicates"))
 	}
+	errs = multierr.Append(errs, validateSourceIncrementResidues(failoverVersionIncrement, clusterGroup))
+
+	return multierr.Append(errs, ValidateUniqueRPCAddresses(clusterGroup))
+}
+
+// validateSourceIncrementResidues validates that no failover version of a cluster can also be resolved to
+// a cluster with a source increment once translated, i.e. the initial versions are not congruent modulo
+// the greatest common divisor of the version increment and the source increment
+func validateSourceIncrementResidues(failoverVersionIncrement int64, clusterGroup map[string]ClusterInformation) error {
+	if failoverVersionIncrement <= 0 {
+		return nil
+	}
 
+	var errs error
+	clusterNames := sortedClusterNames(clusterGroup)
+	for _, sourceClusterName := range clusterNames {
+		sourceInfo := clusterGroup[sourceClusterName]
+		if sourceInfo.SourceIncrement <= 0 || sourceInfo.SourceIncrement == failoverVersionIncrement {
+			continue
+		}
+		divisor := gcd(failoverVersionIncrement, sourceInfo.SourceIncrement)
+		for _, clusterName := range clusterNames {
+			info := clusterGroup[clusterName]
+			if clusterName == sourceClusterName || info.InitialFailoverVersion%divisor != sourceInfo.InitialFailoverVersion%divisor {
+				continue
+			}
+			errs = multierr.Append(errs, fmt.Errorf(
+				"cluster %v: failover versions with initial version %v overlap the versions of cluster %v with source increment %v",
+				clusterName,
+				info.InitialFailoverVersion,
+				sourceClusterName,
+				sourceInfo.SourceIncrement,
+			))
+		}
+	}
+	return errs
+}
+
+func gcd(a int64, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// ValidateUniqueRPCAddresses validates that no two enabled clusters share an RPC address,
+// which would make replication loop back to the same cluster
+func ValidateUniqueRPCAddresses(clusterGroup map[string]ClusterInformation) error {
+	rpcAddressToClusterNames := make(map[string][]string)
+	for clusterName, info := range clusterGroup {
+		if info.Enabled && len(info.RPCAddress) > 0 {
+			rpcAddressToClusterNames[info.RPCAddress] = append(rpcAddressToClusterNames[info.RPCAddress], clusterName)
+		}
+	}
+
+	rpcAddresses := make([]string, 0, len(rpcAddressToClusterNames))
+	for rpcAddress := range rpcAddressToClusterNames {
+		rpcAddresses = append(rpcAddresses, rpcAddress)
+	}
+	sort.Strings(rpcAddresses)
+	var errs error
+	for _, rpcAddress := range rpcAddresses {
+		if clusterNames := rpcAddressToClusterNames[rpcAddress]; len(clusterNames) > 1 {
+			sort.Strings(clusterNames)
+			errs = multierr.Append(errs, fmt.Errorf(
+				"clusters %v share the rpc address %v",
+				strings.Join(clusterNames, ", "),
+				rpcAddress,
+			))
+		}
+	}
 	return errs
 }
 
@@ -170,3 +338,12 @@ func (m *ClusterGroupMetadata) FillDefaults() {
 		m.ClusterGroup[name] = cluster
 	}
 }
+
+func sortedClusterNames(clusterGroup map[string]ClusterInformation) []string {
+	clusterNames := make([]string, 0, len(clusterGroup))
+	for clusterName := range clusterGroup {
+		clusterNames = append(clusterNames, clusterName)
+	}
+	sort.Strings(clusterNames)
+	return clusterNames
+}
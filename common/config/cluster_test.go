@@ -21,7 +21,9 @@
 package config
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,6 +84,15 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "primary cluster is not specified in the cluster group",
 		},
+		{
+			msg: "primary cluster is archival only",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				active := m.ClusterGroup["active"]
+				active.ArchivalOnly = true
+				m.ClusterGroup["active"] = active
+			}),
+			err: "primary cluster is archival only",
+		},
 		{
 			msg: "current cluster is not specified in the cluster group",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -89,6 +100,23 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "current cluster is not specified in the cluster group",
 		},
+		{
+			msg: "primary failover candidate is not specified in the cluster group",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.PrimaryFailoverOrder = []string{"standby", "non-existing"}
+			}),
+			err: "primary failover candidate non-existing is not specified in the cluster group",
+		},
+		{
+			msg: "primary failover candidate is archival only",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				standby := m.ClusterGroup["standby"]
+				standby.ArchivalOnly = true
+				m.ClusterGroup["standby"] = standby
+				m.PrimaryFailoverOrder = []string{"standby"}
+			}),
+			err: "primary failover candidate standby is archival only",
+		},
 		{
 			msg: "version increment is 0",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -103,6 +131,18 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "empty cluster group",
 		},
+		{
+			msg: "more clusters than version increment",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				for i := 0; i < 11; i++ {
+					m.ClusterGroup[fmt.Sprintf("cluster-%v", i)] = ClusterInformation{
+						InitialFailoverVersion: int64(i % 10),
+						RPCTransport:           "grpc",
+					}
+				}
+			}),
+			err: "cluster group has 13 clusters, more than version increment 10, use a larger version increment",
+		},
 		{
 			msg: "cluster with empty name defined",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -117,6 +157,68 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "cluster standby: version increment 1 is smaller than initial version: 2",
 		},
+		{
+			msg: "source increment smaller than initial version",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				standby := m.ClusterGroup["standby"]
+				standby.SourceIncrement = 2
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster standby: source increment 2 is invalid for initial version: 2",
+		},
+		{
+			msg: "negative source increment",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				standby := m.ClusterGroup["standby"]
+				standby.SourceIncrement = -1
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster standby: source increment -1 is invalid for initial version: 2",
+		},
+		{
+			msg: "source increment without overlapping residues",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.FailoverVersionIncrement = 100
+				standby := m.ClusterGroup["standby"]
+				standby.InitialFailoverVersion = 3
+				standby.SourceIncrement = 10
+				m.ClusterGroup["standby"] = standby
+			}),
+		},
+		{
+			msg: "source increment with overlapping residues",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.FailoverVersionIncrement = 100
+				active := m.ClusterGroup["active"]
+				active.InitialFailoverVersion = 13
+				m.ClusterGroup["active"] = active
+				standby := m.ClusterGroup["standby"]
+				standby.InitialFailoverVersion = 3
+				standby.SourceIncrement = 10
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster active: failover versions with initial version 13 overlap the versions of cluster standby with source increment 10",
+		},
+		{
+			msg: "quorum member is not enabled",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				standby := m.ClusterGroup["standby"]
+				standby.Enabled = false
+				standby.QuorumMember = true
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster standby: quorum member is not enabled",
+		},
+		{
+			msg: "negative max replication lag",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				standby := m.ClusterGroup["standby"]
+				standby.MaxReplicationLag = -time.Second
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster standby: max replication lag -1s is negative",
+		},
 		{
 			msg: "empty rpc name",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -135,6 +237,45 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "cluster active: rpc name / address is empty",
 		},
+		{
+			msg: "enabled remote cluster without rpc address",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				standby := m.ClusterGroup["standby"]
+				standby.RPCAddress = ""
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster standby: rpc name / address is empty",
+		},
+		{
+			msg: "disabled remote cluster without rpc address",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				standby := m.ClusterGroup["standby"]
+				standby.Enabled = false
+				standby.RPCAddress = ""
+				m.ClusterGroup["standby"] = standby
+			}),
+		},
+		{
+			msg: "enabled clusters share rpc address",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				standby := m.ClusterGroup["standby"]
+				standby.RPCAddress = m.ClusterGroup["active"].RPCAddress
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "clusters active, standby share the rpc address localhost:8833",
+		},
+		{
+			msg: "disabled cluster shares rpc address",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				standby := m.ClusterGroup["standby"]
+				standby.Enabled = false
+				standby.RPCAddress = m.ClusterGroup["active"].RPCAddress
+				m.ClusterGroup["standby"] = standby
+			}),
+		},
 		{
 			msg: "invalid rpc transport",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -144,6 +285,67 @@ func TestClusterGroupMetadataValidate(t *testing.T) {
 			}),
 			err: "cluster active: rpc transport must tchannel or grpc",
 		},
+		{
+			msg: "empty rpc transport uses the default",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				active := m.ClusterGroup["active"]
+				active.RPCTransport = ""
+				m.ClusterGroup["active"] = active
+			}),
+		},
+		{
+			msg: "empty capability",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				active := m.ClusterGroup["active"]
+				active.Capabilities = []string{"a", ""}
+				m.ClusterGroup["active"] = active
+			}),
+			err: "cluster active: empty capability defined",
+		},
+		{
+			msg: "capability duplicated",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				active := m.ClusterGroup["active"]
+				active.Capabilities = []string{"a", "b", "a"}
+				m.ClusterGroup["active"] = active
+			}),
+			err: "cluster active: capabilities have duplicates",
+		},
+		{
+			msg: "replica cluster is not specified in the cluster group",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				active := m.ClusterGroup["active"]
+				active.ReplicaClusters = []string{"standby", "non-existing"}
+				m.ClusterGroup["active"] = active
+			}),
+			err: "cluster active: replica cluster non-existing is not specified in the cluster group",
+		},
+		{
+			msg: "replica cluster is not enabled",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				active := m.ClusterGroup["active"]
+				active.ReplicaClusters = []string{"standby"}
+				m.ClusterGroup["active"] = active
+				standby := m.ClusterGroup["standby"]
+				standby.Enabled = false
+				m.ClusterGroup["standby"] = standby
+			}),
+			err: "cluster active: replica cluster standby is not enabled",
+		},
+		{
+			msg: "replica cluster is not enabled but allowed",
+			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
+				m.CurrentClusterName = "active"
+				active := m.ClusterGroup["active"]
+				active.ReplicaClusters = []string{"standby"}
+				active.AllowDisabledReplicaClusters = true
+				m.ClusterGroup["active"] = active
+				standby := m.ClusterGroup["standby"]
+				standby.Enabled = false
+				m.ClusterGroup["standby"] = standby
+			}),
+		},
 		{
 			msg: "initial version duplicated",
 			config: modify(validClusterGroupMetadata(), func(m *ClusterGroupMetadata) {
@@ -184,7 +386,7 @@ func validClusterGroupMetadata() *ClusterGroupMetadata {
 				Enabled:                true,
 				InitialFailoverVersion: 0,
 				RPCName:                "cadence-frontend",
-				RPCAddress:             "localhost:7833",
+				RPCAddress:             "localhost:8833",
 				RPCTransport:           "grpc",
 			},
 			"standby": {
@@ -77,7 +77,7 @@ func (d *AttrValidatorImpl) validateDomainReplicationConfigForLocalDomain(
 		}
 	}
 
-	if activeCluster != d.clusterMetadata.GetCurrentClusterName() {
+	if !d.clusterMetadata.IsCurrentCluster(activeCluster) {
 		return &types.BadRequestError{Message: "Invalid local domain active cluster"}
 	}
 
@@ -458,7 +458,7 @@ func (d *handlerImpl) UpdateDomain(
 			return nil, errInvalidGracefulFailover
 		}
 		// must start with the passive -> active cluster
-		if replicationConfig.ActiveClusterName != d.clusterMetadata.GetCurrentClusterName() {
+		if !d.clusterMetadata.IsCurrentCluster(replicationConfig.ActiveClusterName) {
 			return nil, errCannotDoGracefulFailoverFromCluster
 		}
 		if replicationConfig.ActiveClusterName == currentActiveCluster {
@@ -727,6 +727,8 @@ const (
 	DomainFailoverScope
 	// DomainReplicationQueueScope is used in domainreplication queue
 	DomainReplicationQueueScope
+	// ClusterMetadataScope is used by cluster metadata
+	ClusterMetadataScope
 
 	NumCommonScopes
 )
@@ -1524,6 +1526,7 @@ var ScopeDefs = map[ServiceIdx]map[int]scopeDefinition{
 
 		DomainFailoverScope:         {operation: "DomainFailover"},
 		DomainReplicationQueueScope: {operation: "DomainReplicationQueue"},
+		ClusterMetadataScope:        {operation: "ClusterMetadata"},
 	},
 	// Frontend Scope Names
 	Frontend: {
@@ -1936,6 +1939,8 @@ const (
 	ParentClosePolicyProcessorSuccess
 	ParentClosePolicyProcessorFailures
 
+	ClusterMetadataUnknownFailoverVersionCounter
+
 	NumCommonMetrics // Needs to be last on this list for iota numbering
 )
 
@@ -2498,6 +2503,9 @@ var MetricDefs = map[ServiceIdx]map[int]metricDefinition{
 		DomainReplicationQueueSizeErrorCount: {metricName: "domain_replication_queue_failed", metricType: Counter},
 		ParentClosePolicyProcessorSuccess:    {metricName: "parent_close_policy_processor_requests", metricType: Counter},
 		ParentClosePolicyProcessorFailures:   {metricName: "parent_close_policy_processor_errors", metricType: Counter},
+		ClusterMetadataUnknownFailoverVersionCounter: {
+			metricName: "cluster_metadata_unknown_failover_version", metricType: Counter,
+		},
 	},
 	History: {
 		TaskRequests:             {metricName: "task_requests", metricType: Counter},
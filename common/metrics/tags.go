@@ -48,6 +48,7 @@ const (
 	transport              = "transport"
 	caller                 = "caller"
 	signalName             = "signalName"
+	failoverVersionResidue = "failover_version_residue"
 
 	allValue     = "all"
 	unknownValue = "_unknown_"
@@ -161,6 +162,11 @@ func KafkaPartitionTag(value int32) Tag {
 	return simpleMetric{key: kafkaPartition, value: strconv.Itoa(int(value))}
 }
 
+// FailoverVersionResidueTag returns a new failover version residue tag.
+func FailoverVersionResidueTag(value int64) Tag {
+	return simpleMetric{key: failoverVersionResidue, value: strconv.FormatInt(value, 10)}
+}
+
 // TransportTag returns a new RPC Transport type tag.
 func TransportTag(value string) Tag {
 	return simpleMetric{key: transport, value: value}
@@ -1286,7 +1286,7 @@ func (c *contextImpl) ReapplyEvents(
 	defer cancel()
 
 	activeCluster := domainEntry.GetReplicationConfig().ActiveClusterName
-	if activeCluster == c.shard.GetClusterMetadata().GetCurrentClusterName() {
+	if c.shard.GetClusterMetadata().IsCurrentCluster(activeCluster) {
 		return c.shard.GetEngine().ReapplyEvents(
 			ctx,
 			domainID,
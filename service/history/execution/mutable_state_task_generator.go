@@ -626,7 +626,7 @@ func (r *mutableStateTaskGeneratorImpl) GenerateCrossClusterRecordChildCompleted
 	targetCluster string,
 	parentInfo *types.ParentExecutionInfo,
 ) error {
-	if targetCluster == r.clusterMetadata.GetCurrentClusterName() {
+	if r.clusterMetadata.IsCurrentCluster(targetCluster) {
 		// this should not happen
 		return errors.New("unable to create cross-cluster task for current cluster")
 	}
@@ -652,7 +652,7 @@ func (r *mutableStateTaskGeneratorImpl) GenerateCrossClusterApplyParentClosePoli
 	targetCluster string,
 	childDomainIDs map[string]struct{},
 ) error {
-	if targetCluster == r.clusterMetadata.GetCurrentClusterName() {
+	if r.clusterMetadata.IsCurrentCluster(targetCluster) {
 		// this should not happen
 		return errors.New("unable to create cross-cluster task for current cluster")
 	}
@@ -678,7 +678,7 @@ func (r *mutableStateTaskGeneratorImpl) GenerateFromTransferTask(
 	task *persistence.TransferTaskInfo,
 	targetCluster string,
 ) error {
-	if targetCluster == r.clusterMetadata.GetCurrentClusterName() {
+	if r.clusterMetadata.IsCurrentCluster(targetCluster) {
 		// this should not happen
 		return errors.New("unable to create cross-cluster task for current cluster")
 	}
@@ -814,7 +814,7 @@ func (r *mutableStateTaskGeneratorImpl) GenerateFromCrossClusterTask(
 			return err
 		}
 		targetCluster = targetDomainEntry.GetReplicationConfig().ActiveClusterName
-		if targetCluster == r.clusterMetadata.GetCurrentClusterName() {
+		if r.clusterMetadata.IsCurrentCluster(targetCluster) {
 			generateTransferTask = true
 		}
 	}
@@ -960,7 +960,7 @@ func (r *mutableStateTaskGeneratorImpl) isCrossClusterTask(
 
 	// case 3: target cluster is the same as source domain active cluster
 	// which is current cluster since source domain is active
-	if targetCluster == r.clusterMetadata.GetCurrentClusterName() {
+	if r.clusterMetadata.IsCurrentCluster(targetCluster) {
 		return "", false, nil
 	}
 
@@ -54,7 +54,7 @@ type (
 	}
 
 	workflowImpl struct {
-		clusterMetadata cluster.Metadata
+		clusterMetadata cluster.ReadOnlyMetadata
 
 		ctx          context.Context
 		context      Context
@@ -66,7 +66,7 @@ type (
 // NewWorkflow creates a new NDC workflow
 func NewWorkflow(
 	ctx context.Context,
-	clusterMetadata cluster.Metadata,
+	clusterMetadata cluster.ReadOnlyMetadata,
 	context Context,
 	mutableState MutableState,
 	releaseFn ReleaseFunc,
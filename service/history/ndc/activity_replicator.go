@@ -53,7 +53,7 @@ type (
 
 	activityReplicatorImpl struct {
 		executionCache  *execution.Cache
-		clusterMetadata cluster.Metadata
+		clusterMetadata cluster.ReadOnlyMetadata
 		logger          log.Logger
 	}
 )
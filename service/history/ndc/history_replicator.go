@@ -54,7 +54,7 @@ type (
 
 	historyReplicatorImpl struct {
 		shard              shard.Context
-		clusterMetadata    cluster.Metadata
+		clusterMetadata    cluster.ReadOnlyMetadata
 		historyV2Manager   persistence.HistoryManager
 		historySerializer  persistence.PayloadSerializer
 		metricsClient      metrics.Client
@@ -686,7 +686,7 @@ func (r *historyReplicatorImpl) notify(
 	clusterName string,
 	now time.Time,
 ) {
-	if clusterName == r.clusterMetadata.GetCurrentClusterName() {
+	if r.clusterMetadata.IsCurrentCluster(clusterName) {
 		// this is a valid use case for testing, but not for production
 		r.logger.Warn("nDCHistoryReplicator applying events generated by current cluster")
 		return
@@ -91,7 +91,7 @@ var (
 )
 
 func newReplicationTask(
-	clusterMetadata cluster.Metadata,
+	clusterMetadata cluster.ReadOnlyMetadata,
 	historySerializer persistence.PayloadSerializer,
 	taskStartTime time.Time,
 	logger log.Logger,
@@ -146,7 +146,7 @@ type (
 	transactionManagerImpl struct {
 		shard            shard.Context
 		executionCache   *execution.Cache
-		clusterMetadata  cluster.Metadata
+		clusterMetadata  cluster.ReadOnlyMetadata
 		historyV2Manager persistence.HistoryManager
 		serializer       persistence.PayloadSerializer
 		metricsClient    metrics.Client
@@ -570,7 +570,7 @@ func (s *contextImpl) UpdateTimerMaxReadLevel(cluster string) time.Time {
 	defer s.Unlock()
 
 	currentTime := s.GetTimeSource().Now()
-	if cluster != "" && cluster != s.GetClusterMetadata().GetCurrentClusterName() {
+	if cluster != "" && !s.GetClusterMetadata().IsCurrentCluster(cluster) {
 		currentTime = s.remoteClusterCurrentTime[cluster]
 	}
 
@@ -1322,7 +1322,7 @@ func (s *contextImpl) allocateTimerIDsLocked(
 func (s *contextImpl) SetCurrentTime(cluster string, currentTime time.Time) {
 	s.Lock()
 	defer s.Unlock()
-	if cluster != s.GetClusterMetadata().GetCurrentClusterName() {
+	if !s.GetClusterMetadata().IsCurrentCluster(cluster) {
 		prevTime := s.remoteClusterCurrentTime[cluster]
 		if prevTime.Before(currentTime) {
 			s.remoteClusterCurrentTime[cluster] = currentTime
@@ -1335,7 +1335,7 @@ func (s *contextImpl) SetCurrentTime(cluster string, currentTime time.Time) {
 func (s *contextImpl) GetCurrentTime(cluster string) time.Time {
 	s.RLock()
 	defer s.RUnlock()
-	if cluster != s.GetClusterMetadata().GetCurrentClusterName() {
+	if !s.GetClusterMetadata().IsCurrentCluster(cluster) {
 		return s.remoteClusterCurrentTime[cluster]
 	}
 	return s.GetTimeSource().Now()
@@ -1522,7 +1522,7 @@ func acquireShard(
 	remoteClusterCurrentTime := make(map[string]time.Time)
 	timerMaxReadLevelMap := make(map[string]time.Time)
 	for clusterName := range shardItem.GetClusterMetadata().GetEnabledClusterInfo() {
-		if clusterName != shardItem.GetClusterMetadata().GetCurrentClusterName() {
+		if !shardItem.GetClusterMetadata().IsCurrentCluster(clusterName) {
 			if currentTime, ok := shardInfo.ClusterTimerAckLevel[clusterName]; ok {
 				remoteClusterCurrentTime[clusterName] = currentTime
 				timerMaxReadLevelMap[clusterName] = currentTime
@@ -1432,7 +1432,7 @@ func (t *transferActiveTaskExecutor) isCrossClusterTask(
 	}
 
 	targetCluster := targetDomainEntry.GetReplicationConfig().ActiveClusterName
-	if targetCluster != t.shard.GetClusterMetadata().GetCurrentClusterName() {
+	if !t.shard.GetClusterMetadata().IsCurrentCluster(targetCluster) {
 		return targetCluster, true
 	}
 	return "", false